@@ -0,0 +1,257 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Параметр клиента Kafka в формате .properties
+type clientProperty struct {
+	Key   string
+	Value string
+}
+
+// Экранируем значение для .properties: обратный слэш и переводы строк
+func escapePropertyValue(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "\r", "", "\n", `\n`)
+	return replacer.Replace(value)
+}
+
+// Экранируем значение для строки в кавычках внутри sasl.jaas.config
+func jaasQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Формируем sasl.jaas.config из модуля входа и его параметров
+func jaasConfig(loginModule string, options ...string) string {
+	parts := append([]string{loginModule, "required"}, options...)
+	return strings.Join(parts, " ") + ";"
+}
+
+// Параметры SASL для механизма из kafka.sasl.mechanism
+func saslClientProperties() ([]clientProperty, error) {
+	mechanism := viper.GetString("kafka.sasl.mechanism")
+	props := []clientProperty{{"sasl.mechanism", mechanism}}
+
+	switch mechanism {
+	case "PLAIN":
+		props = append(props, clientProperty{"sasl.jaas.config", jaasConfig(
+			"org.apache.kafka.common.security.plain.PlainLoginModule",
+			"username="+jaasQuote(viper.GetString("kafka.sasl.username")),
+			"password="+jaasQuote(viper.GetString("kafka.sasl.password")),
+		)})
+	case "SCRAM-SHA-256", "SCRAM-SHA-512":
+		props = append(props, clientProperty{"sasl.jaas.config", jaasConfig(
+			"org.apache.kafka.common.security.scram.ScramLoginModule",
+			"username="+jaasQuote(viper.GetString("kafka.sasl.username")),
+			"password="+jaasQuote(viper.GetString("kafka.sasl.password")),
+		)})
+	case "OAUTHBEARER":
+		options := []string{
+			"clientId=" + jaasQuote(viper.GetString("kafka.sasl.oauth.clientId")),
+			"clientSecret=" + jaasQuote(viper.GetString("kafka.sasl.oauth.clientSecret")),
+		}
+		if scopes := viper.GetStringSlice("kafka.sasl.oauth.scopes"); len(scopes) > 0 {
+			options = append(options, "scope="+jaasQuote(strings.Join(scopes, " ")))
+		}
+		extensions := viper.GetStringMapString("kafka.sasl.oauth.extensions")
+		keys := make([]string, 0, len(extensions))
+		for key := range extensions {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			options = append(options, "extension_"+key+"="+jaasQuote(extensions[key]))
+		}
+		props = append(props,
+			clientProperty{"sasl.oauthbearer.token.endpoint.url", viper.GetString("kafka.sasl.oauth.tokenEndpoint")},
+			clientProperty{"sasl.login.callback.handler.class", "org.apache.kafka.common.security.oauthbearer.OAuthBearerLoginCallbackHandler"},
+			clientProperty{"sasl.jaas.config", jaasConfig("org.apache.kafka.common.security.oauthbearer.OAuthBearerLoginModule", options...)},
+		)
+	case "GSSAPI":
+		serviceName := viper.GetString("kafka.sasl.gssapi.serviceName")
+		if serviceName == "" {
+			serviceName = "kafka"
+		}
+		principal := viper.GetString("kafka.sasl.gssapi.username")
+		if realm := viper.GetString("kafka.sasl.gssapi.realm"); realm != "" && principal != "" {
+			principal += "@" + realm
+		}
+		var options []string
+		if keytab := viper.GetString("kafka.sasl.gssapi.keytab"); keytab != "" {
+			options = append(options, "useKeyTab=true", "storeKey=true", "keyTab="+jaasQuote(keytab))
+		} else {
+			options = append(options, "useTicketCache=true")
+			if ccache := viper.GetString("kafka.sasl.gssapi.ccache"); ccache != "" {
+				options = append(options, "ticketCache="+jaasQuote(ccache))
+			}
+		}
+		if principal != "" {
+			options = append(options, "principal="+jaasQuote(principal))
+		}
+		props = append(props,
+			clientProperty{"sasl.kerberos.service.name", serviceName},
+			clientProperty{"sasl.jaas.config", jaasConfig("com.sun.security.auth.module.Krb5LoginModule", options...)},
+		)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый механизм SASL %q", mechanism)
+	}
+	return props, nil
+}
+
+// Параметры SSL: truststore и keystore из kafka.tls.truststore/keystore,
+// при их отсутствии - PEM файлы kafka.tls.caFile, certFile и keyFile
+func sslClientProperties() ([]clientProperty, error) {
+	var props []clientProperty
+
+	if location := viper.GetString("kafka.tls.truststore.location"); location != "" {
+		props = append(props, clientProperty{"ssl.truststore.location", location})
+		if storeType := viper.GetString("kafka.tls.truststore.type"); storeType != "" {
+			props = append(props, clientProperty{"ssl.truststore.type", storeType})
+		}
+		if password := viper.GetString("kafka.tls.truststore.password"); password != "" {
+			props = append(props, clientProperty{"ssl.truststore.password", password})
+		}
+	} else if caFile := viper.GetString("kafka.tls.caFile"); caFile != "" {
+		props = append(props,
+			clientProperty{"ssl.truststore.type", "PEM"},
+			clientProperty{"ssl.truststore.location", caFile},
+		)
+	}
+
+	if location := viper.GetString("kafka.tls.keystore.location"); location != "" {
+		props = append(props, clientProperty{"ssl.keystore.location", location})
+		if storeType := viper.GetString("kafka.tls.keystore.type"); storeType != "" {
+			props = append(props, clientProperty{"ssl.keystore.type", storeType})
+		}
+		if password := viper.GetString("kafka.tls.keystore.password"); password != "" {
+			props = append(props, clientProperty{"ssl.keystore.password", password})
+		}
+		if keyPassword := viper.GetString("kafka.tls.keystore.keyPassword"); keyPassword != "" {
+			props = append(props, clientProperty{"ssl.key.password", keyPassword})
+		}
+	} else if certFile := viper.GetString("kafka.tls.certFile"); certFile != "" {
+		// Java клиент принимает PEM сертификат и ключ только в одном файле или в значении параметра
+		cert, err := os.ReadFile(certFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения kafka.tls.certFile: %v", err)
+		}
+		key, err := os.ReadFile(viper.GetString("kafka.tls.keyFile"))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения kafka.tls.keyFile: %v", err)
+		}
+		props = append(props,
+			clientProperty{"ssl.keystore.type", "PEM"},
+			clientProperty{"ssl.keystore.certificate.chain", strings.TrimSpace(string(cert))},
+			clientProperty{"ssl.keystore.key", strings.TrimSpace(string(key))},
+		)
+	}
+
+	switch strings.TrimPrefix(strings.ToUpper(viper.GetString("kafka.tls.minVersion")), "TLSV") {
+	case "1.0":
+		props = append(props, clientProperty{"ssl.enabled.protocols", "TLSv1,TLSv1.1,TLSv1.2,TLSv1.3"})
+	case "1.1":
+		props = append(props, clientProperty{"ssl.enabled.protocols", "TLSv1.1,TLSv1.2,TLSv1.3"})
+	case "1.3":
+		props = append(props, clientProperty{"ssl.enabled.protocols", "TLSv1.3"})
+	case "", "1.2":
+		props = append(props, clientProperty{"ssl.enabled.protocols", "TLSv1.2,TLSv1.3"})
+	}
+
+	// У Java клиента нет параметров для проверки сертификата по другому имени и для отключения
+	// проверки цепочки сертификатов: ближайший аналог - отключение проверки имени хоста брокера
+	serverName := viper.GetString("kafka.tls.serverName")
+	skipVerify := viper.GetBool("kafka.tls.insecureSkipVerify")
+	if serverName != "" || skipVerify {
+		props = append(props, clientProperty{"ssl.endpoint.identification.algorithm", ""})
+	}
+	if serverName != "" && !skipVerify {
+		log.Printf("⚠️ kafka.tls.serverName не поддерживается Java клиентом: проверка имени хоста брокера отключена, " +
+			"сертификат проверяется только по truststore")
+	}
+	if skipVerify {
+		log.Printf("⚠️ kafka.tls.insecureSkipVerify: Java клиент отключает только проверку имени хоста брокера, " +
+			"цепочка сертификатов по-прежнему проверяется по truststore")
+	}
+	return props, nil
+}
+
+// Формируем параметры клиента Kafka по текущему профилю конфигурации
+func clientProperties() ([]clientProperty, error) {
+	saslEnabled := viper.GetBool("kafka.sasl.enabled")
+	tlsEnabled := viper.GetBool("kafka.tls.enabled")
+
+	var securityProtocol string
+	switch {
+	case saslEnabled && tlsEnabled:
+		securityProtocol = "SASL_SSL"
+	case saslEnabled:
+		securityProtocol = "SASL_PLAINTEXT"
+	case tlsEnabled:
+		securityProtocol = "SSL"
+	default:
+		securityProtocol = "PLAINTEXT"
+	}
+
+	props := []clientProperty{
+		{"bootstrap.servers", strings.Join(viper.GetStringSlice("kafka.broker"), ",")},
+		{"security.protocol", securityProtocol},
+	}
+	if saslEnabled {
+		saslProps, err := saslClientProperties()
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, saslProps...)
+	}
+	if tlsEnabled {
+		sslProps, err := sslClientProperties()
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, sslProps...)
+	}
+	return props, nil
+}
+
+// Записываем параметры клиента в формате .properties
+func writeClientProperties(w io.Writer, props []clientProperty) error {
+	for _, prop := range props {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", prop.Key, escapePropertyValue(prop.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Выводим параметры клиента для kafka-*.sh и других клиентов Kafka.
+// При output "-" параметры выводятся в stdout, иначе записываются в файл, доступный только владельцу
+func printClientConfig(output string) error {
+	props, err := clientProperties()
+	if err != nil {
+		return err
+	}
+	if output == "" || output == "-" {
+		return writeClientProperties(os.Stdout, props)
+	}
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла %s: %v", output, err)
+	}
+	if err := writeClientProperties(file, props); err != nil {
+		file.Close()
+		return fmt.Errorf("ошибка записи файла %s: %v", output, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %v", output, err)
+	}
+	log.Printf("Параметры клиента сохранены в %s", output)
+	return nil
+}
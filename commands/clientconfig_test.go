@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSSLClientPropertiesTLSOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+		want   []clientProperty
+	}{
+		{
+			name:   "по умолчанию",
+			config: map[string]any{},
+			want:   []clientProperty{{"ssl.enabled.protocols", "TLSv1.2,TLSv1.3"}},
+		},
+		{
+			name:   "minVersion 1.1",
+			config: map[string]any{"kafka.tls.minVersion": "TLSv1.1"},
+			want:   []clientProperty{{"ssl.enabled.protocols", "TLSv1.1,TLSv1.2,TLSv1.3"}},
+		},
+		{
+			name:   "serverName",
+			config: map[string]any{"kafka.tls.serverName": "kafka.example.com"},
+			want: []clientProperty{
+				{"ssl.enabled.protocols", "TLSv1.2,TLSv1.3"},
+				{"ssl.endpoint.identification.algorithm", ""},
+			},
+		},
+		{
+			name:   "insecureSkipVerify",
+			config: map[string]any{"kafka.tls.insecureSkipVerify": true, "kafka.tls.minVersion": "1.3"},
+			want: []clientProperty{
+				{"ssl.enabled.protocols", "TLSv1.3"},
+				{"ssl.endpoint.identification.algorithm", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for key, value := range tt.config {
+				viper.Set(key, value)
+			}
+
+			props, err := sslClientProperties()
+			if err != nil {
+				t.Fatalf("sslClientProperties: %v", err)
+			}
+			if !slices.Equal(props, tt.want) {
+				t.Errorf("параметры %q, ожидалось %q", props, tt.want)
+			}
+		})
+	}
+}
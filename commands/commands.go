@@ -1,26 +1,48 @@
 package commands
 
 import (
-	"encoding/json"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
+	"path"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/IBM/sarama"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
+// Админ-клиент поверх общего клиента команд. Close у sarama закрывает и общий клиент,
+// поэтому здесь он ничего не делает: общий клиент закрывается один раз в main
+type sharedClusterAdmin struct {
+	sarama.ClusterAdmin
+}
+
+func (a sharedClusterAdmin) Close() error {
+	return nil
+}
+
+func newClusterAdmin(client sarama.Client) (sarama.ClusterAdmin, error) {
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return nil, err
+	}
+	return sharedClusterAdmin{admin}, nil
+}
+
 type Topic struct {
 	Topics  []map[string]string `json:"topics"`
 	Version int                 `json:"version"`
 }
 
 func (c *Topic) topicList(client sarama.Client) (*Topic, error) {
-	admin, err := sarama.NewClusterAdminFromClient(client)
+	admin, err := newClusterAdmin(client)
 	if err != nil {
 		log.Printf("Ошибка создания клиента: %v", err)
 		return nil, err
@@ -50,50 +72,19 @@ func (c *Topic) topicList(client sarama.Client) (*Topic, error) {
 	return c, nil
 }
 
-// Получаем список свободных id реплик (брокеров) для каждого топика
-func (c *Topic) whoTopicPart(client sarama.Client, configFile string, brokerIDs []int32) (map[string][]int32, map[string][]int32, error) {
-
-	type TopicConfig struct {
-		CleanupPolicy     string `yaml:"cleanup.policy"`
-		MaxMessageBytes   int    `yaml:"max.message.bytes"`
-		MinInsyncReplicas int    `yaml:"min.insync.replicas"`
-		RetentionBytes    int    `yaml:"retention.bytes"`
-		RetentionMs       int    `yaml:"retention.ms"`
-		SegmentBytes      int    `yaml:"segment.bytes"`
-		Partitions        int    `yaml:"partitions"`
-		Replicas          int    `yaml:"replicas"`
-	}
-
-	// Чтение YAML файла
-	data, err := os.ReadFile(configFile)
+// Получаем требуемый фактор репликации и список свободных id реплик (брокеров) для каждого топика.
+// В результат попадают только топики, у которых фактор репликации отличается от указанного в файле
+func (c *Topic) whoTopicPart(client sarama.Client, configFile string, brokerIDs []int32) (map[string]int, map[string][]int32, map[string][]int32, error) {
+	topicsConfig, err := readTopicsFile(configFile)
 	if err != nil {
-		log.Printf("Ошибка чтения файла %s: %v", configFile, err)
-		return nil, nil, err
-	}
-
-	// TopicsFile представляет структуру YAML файла с топиками
-	type TopicsFile struct {
-		Topics map[string]TopicConfig `yaml:"topics"`
-	}
-
-	// Парсинг YAML в карту
-	var topicsFile TopicsFile
-	err = yaml.Unmarshal(data, &topicsFile)
-	if err != nil {
-		log.Printf("Ошибка парсинга YAML файла: %v", err)
-		return nil, nil, err
-	}
-	// Проверка наличия секции "topics"
-	topicsConfig := topicsFile.Topics
-	if len(topicsConfig) == 0 {
-		return nil, nil, fmt.Errorf("отсутствует секция 'topics'")
+		return nil, nil, nil, err
 	}
 
 	// Создаем админ-клиент
-	admin, err := sarama.NewClusterAdminFromClient(client)
+	admin, err := newClusterAdmin(client)
 	if err != nil {
 		log.Printf("Ошибка создания админ-клиента: %v", err)
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	defer admin.Close()
 
@@ -101,926 +92,1062 @@ func (c *Topic) whoTopicPart(client sarama.Client, configFile string, brokerIDs
 	topics, err := admin.ListTopics()
 	if err != nil {
 		log.Printf("Ошибка получения списка топиков: %v", err)
-		return nil, nil, err
-	}
-
-	topicList := make([]string, 0)
-
-	// Заполняем список топиков из kafka
-	for topicName := range topics {
-		if topicName == "__consumer_offsets" {
-			continue
-		}
-		topicList = append(topicList, topicName)
-		// log.Printf("Топик в kafka: %s", topicName)
+		return nil, nil, nil, err
 	}
 
+	// Создаем результирующую мапу для требуемого фактора репликации каждого топика
+	replicationFactors := make(map[string]int)
 	// Создаем результирующую мапу для id реплик (брокеров) для каждого топика
 	replicaBrokerId := make(map[string][]int32)
 	// Создаем результирующую мапу для id новых брокеров для каждого топика
 	freeReplicaBrokerId := make(map[string][]int32)
 	// Для каждого топика из файла конфигурации проверяем текущее колличество брокеров для реплики
 	// и то что указано в конфигурации
-	for topicNameConfig := range topicsConfig {
+	for topicNameConfig, params := range topicsConfig {
 		log.Printf("Топик в конфиге: %s", topicNameConfig)
+
+		// Проверяем наличие топиков которые указаны в конфигурации и их наличие в kafka
+		if _, ok := topics[topicNameConfig]; !ok {
+			return nil, nil, nil, fmt.Errorf("топик %s отсутствует в Kafka", topicNameConfig)
+		}
+
+		detail, err := topicDetailFromParams(params)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("топик %s: %v", topicNameConfig, err)
+		}
+		replicas := int(detail.ReplicationFactor)
+		if replicas <= 0 {
+			return nil, nil, nil, fmt.Errorf("для топика %s не указан параметр replicas", topicNameConfig)
+		}
+		if replicas > len(brokerIDs) {
+			return nil, nil, nil, fmt.Errorf("недостаточно брокеров для репликации топика %s: имеется %d, требуется %d", topicNameConfig, len(brokerIDs), replicas)
+		}
+
 		// Получаем метаданные топика
 		metadata, err := admin.DescribeTopics([]string{topicNameConfig})
 		if err != nil {
 			log.Printf("Ошибка получения метаданных для топика %s: %v", topicNameConfig, err)
+			return nil, nil, nil, err
+		}
+		if len(metadata) == 0 {
 			continue
 		}
 
-		// Проверяем наличие топиков которые указаны в конфигурации и их наличие в kafka
-
-		// log.Printf("Топик в kafka: %s", topic)
-		if !slices.Contains(topicList, topicNameConfig) {
-			return nil, nil, fmt.Errorf("топик %s отсутствует в Kafka", topicNameConfig)
-
+		// Собираем ID брокеров со всех партиций топика
+		var replicaBrokerID []int32
+		changed := false
+		for _, partition := range metadata[0].Partitions {
+			for _, id := range partition.Replicas {
+				if !slices.Contains(replicaBrokerID, id) {
+					replicaBrokerID = append(replicaBrokerID, id)
+				}
+			}
+			if len(partition.Replicas) != replicas {
+				changed = true
+			}
 		}
+		slices.Sort(replicaBrokerID)
+		log.Printf("Текущие реплики на брокерах: %v", replicaBrokerID)
 
-		if len(metadata) > 0 {
-			var replicaBrokerID []int32
-
-			// Собираем ID брокеров с 0 партиции
-			for _, partition := range metadata[0].Partitions {
-				replicaBrokerID = partition.Replicas
+		if !changed {
+			log.Printf("Топик %s уже имеет фактор репликации %d", topicNameConfig, replicas)
+			continue
+		}
 
-				log.Printf("Текущие реплики на брокерах: %v", replicaBrokerID)
-			}
-			// Если в кластере то же значение реплик что и в конфиге или больше то выводим ошибку
-			if len(replicaBrokerID) >= topicsConfig[topicNameConfig].Replicas {
-				log.Printf("Длина: %d", len(replicaBrokerID))
-				return nil, nil, fmt.Errorf("недостаточно брокеров для репликации: имеется %d, требуется больше чем %d", len(replicaBrokerID), topicsConfig[topicNameConfig].Replicas)
-			} else {
-				replicaBrokerId[topicNameConfig] = replicaBrokerID
-				// Сравниваем id брокеров в кластере с id брокерами топика, которые свободны в кластере добавляем в мапу
-				for _, id := range brokerIDs {
-					if !slices.Contains(replicaBrokerID, id) {
-						freeReplicaBrokerId[topicNameConfig] = append(freeReplicaBrokerId[topicNameConfig], id)
-
-					}
-				}
-				log.Printf("Неиспользуемые брокеры для реплик: %d", freeReplicaBrokerId[topicNameConfig])
+		replicationFactors[topicNameConfig] = replicas
+		replicaBrokerId[topicNameConfig] = replicaBrokerID
+		// Сравниваем id брокеров в кластере с id брокерами топика, которые свободны в кластере добавляем в мапу
+		for _, id := range brokerIDs {
+			if !slices.Contains(replicaBrokerID, id) {
+				freeReplicaBrokerId[topicNameConfig] = append(freeReplicaBrokerId[topicNameConfig], id)
 			}
 		}
+		log.Printf("Неиспользуемые брокеры для реплик: %d", freeReplicaBrokerId[topicNameConfig])
 	}
-	return freeReplicaBrokerId, replicaBrokerId, nil
+	return replicationFactors, freeReplicaBrokerId, replicaBrokerId, nil
 }
 
-// требуется дописать
-func (c *Topic) topicGenerateReplacationFactor(configFile string, freeReplicaBrokerId map[string][]int32, replicaBrokerId map[string][]int32) error {
-
-	// type TopicConfig struct {
-	// 	CleanupPolicy     string `yaml:"cleanup.policy"`
-	// 	MaxMessageBytes   int    `yaml:"max.message.bytes"`
-	// 	MinInsyncReplicas int    `yaml:"min.insync.replicas"`
-	// 	RetentionBytes    int    `yaml:"retention.bytes"`
-	// 	RetentionMs       int    `yaml:"retention.ms"`
-	// 	SegmentBytes      int    `yaml:"segment.bytes"`
-	// 	Partitions        int    `yaml:"partitions"`
-	// 	Replicas          int    `yaml:"replicas"`
-	// }
-
-	// // Чтение YAML файла
-	// data, err := os.ReadFile(configFile)
-	// if err != nil {
-	// 	log.Printf("Ошибка чтения файла %s: %v", configFile, err)
-	// 	return err
-	// }
-
-	// // TopicsFile представляет структуру YAML файла с топиками
-	// type TopicsFile struct {
-	// 	Topics map[string]TopicConfig `yaml:"topics"`
-	// }
-
-	// // Парсинг YAML в карту
-	// var topicsFile TopicsFile
-	// err = yaml.Unmarshal(data, &topicsFile)
-	// if err != nil {
-	// 	log.Printf("Ошибка парсинга YAML файла: %v", err)
-	// 	return err
-	// }
-
-	// // Проверка наличия секции "topics"
-	// topicsConfig := topicsFile.Topics
-	// if len(topicsConfig) == 0 {
-	// 	return fmt.Errorf("отсутствует секция 'topics'")
-	// }
-
-	// topicList := make([]string, 0)
-
-	// Инициализируем структуру Topic
-	topicList := &Topic{
-		Version: 1,
-		Topics:  make([]map[string]string, 0),
-	}
-	for topicName := range replicaBrokerId {
-		topicList.Topics = append(topicList.Topics, map[string]string{"topic": topicName})
-	}
-
-	// for topicName := range topicsConfig {
-	// 	topicList = append(topicList, topicName)
-	// }
-
-	// Преобраузем список топиков в JSON
-	jsonData, err := json.MarshalIndent(topicList, "", "  ")
+// Читаем YAML файл с описанием топиков и возвращаем секцию "topics"
+func readTopicsFile(filePath string) (map[string]map[string]interface{}, error) {
+	// Чтение YAML файла
+	data, err := os.ReadFile(filePath)
 	if err != nil {
-		log.Printf("Ошибка парсинга JSON: %v", err)
-		return err
+		log.Printf("Ошибка чтения файла %s: %v", filePath, err)
+		return nil, err
 	}
 
-	// Получаем Значения из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0] // Берем первый элемент из среза
-	} else {
-		log.Printf("Список брокеров пуст")
-		return fmt.Errorf("список брокеров пуст")
-	}
-
-	// Сравнимаем текущие брокер-реплики с фактором репликации
-
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
-
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
-
-	for _, replicaBrokersIDExists := range replicaBrokerId {
-		// подготовка json для увеличения репликации с текущими репликами
-		commandStr := fmt.Sprintf(`
-		echo '%s' > /tmp/config.properties && \
-		echo '%s' > /tmp/replication-factor.json && \
-		RESULT=$(kafka-reassign-partitions.sh --bootstrap-server "%s" \
-		--topics-to-move-json-file "/tmp/replication-factor.json" \
-		--broker-list "%s" --generate \
-		--command-config /tmp/config.properties)
-		if [ $? -ne 0 ]; then
-			echo $RESULT >&2
-			rm /tmp/config.properties /tmp/replication-factor.json
-			exit 1
-		fi && \
-		echo "$RESULT" | awk '
-			/Proposed partition reassignment configuration/,0 {
-				if (!/Proposed partition reassignment configuration/) {
-					print > "/tmp/increase-replication-factor.json"
-				}
-			}
-		' && \
-		rm /tmp/config.properties`, config, string(jsonData), broker, replicaBrokersIDExists)
+	// Парсинг YAML в карту
+	var values map[string]map[string]map[string]interface{}
+	err = yaml.Unmarshal(data, &values)
+	if err != nil {
+		log.Printf("Ошибка парсинга YAML файла: %v", err)
+		return nil, err
+	}
 
-		cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("%s", string(output))
-		}
-	}
-	for _, freeReplicaBrokerId := range freeReplicaBrokerId {
-		// подготовка json для увеличения репликации с новыми брокерами
-		commandFinalStr := fmt.Sprintf(`
-		echo '%s' > /tmp/config.properties && \
-		echo '%s' > /tmp/replication-factor.json && \
-		RESULT=$(kafka-reassign-partitions.sh --bootstrap-server "%s" \
-		--reassignment-json-file "/tmp/increase-replication-factor.json" \
-		--broker-list "%s" --generate \
-		--command-config /tmp/config.properties)
-		if [ $? -ne 0 ]; then
-			echo $RESULT >&2
-			rm /tmp/config.properties /tmp/replication-factor.json
-			exit 1
-		fi && \
-		echo "$RESULT" | awk '
-			/Proposed partition reassignment configuration/,0 {
-				if (!/Proposed partition reassignment configuration/) {
-					print > "/tmp/increase-replication-factor.json"
-				}
-			}
-		' && \
-		rm /tmp/config.properties`, config, string(jsonData), broker, freeReplicaBrokerId)
+	// Проверка наличия секции "topics"
+	topicsConfig, exists := values["topics"]
+	if !exists {
+		return nil, fmt.Errorf("отсутствует секция 'topics'")
+	}
+	return topicsConfig, nil
+}
 
-		cmdFinal := exec.Command("docker", "exec", "kafka", "bash", "-c", commandFinalStr)
-		outputFinal, errFinal := cmdFinal.CombinedOutput()
-		if errFinal != nil {
-			return fmt.Errorf("%s", string(outputFinal))
-		}
+// Ключ YAML с явным распределением реплик для новых партиций при увеличении их количества
+const newPartitionAssignmentKey = "newPartitionAssignment"
 
-	}
-	return nil
+// Параметры топика в YAML, которые не являются параметрами конфигурации Kafka
+func isTopicSpecKey(key string) bool {
+	return key == "partitions" || key == "replicas" || key == newPartitionAssignmentKey
 }
 
-func (c *Topic) topicGenerateReassignPart(topicList *Topic) error {
-	// Преобраузем список топиков в JSON
-	jsonData, err := json.MarshalIndent(topicList, "", "  ")
-	if err != nil {
-		log.Printf("Ошибка парсинга JSON: %v", err)
-		return err
+// Преобразуем параметры топика из YAML в TopicDetail для ClusterAdmin.
+// Если partitions или replicas не указаны, используется значение по умолчанию брокера (-1)
+func topicDetailFromParams(params map[string]interface{}) (*sarama.TopicDetail, error) {
+	detail := &sarama.TopicDetail{
+		NumPartitions:     -1,
+		ReplicationFactor: -1,
+		ConfigEntries:     make(map[string]*string),
 	}
 
-	// Получаем Значения из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0] // Берем первый элемент из среза
-	} else {
-		log.Printf("Список брокеров пуст")
-		return fmt.Errorf("список брокеров пуст")
-	}
-
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
-	brokerListId := viper.GetString("container.brokerList")
-
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
-
-	commandStr := fmt.Sprintf(`
-		echo '%s' > /tmp/config.properties && \
-		echo '%s' > /tmp/topics-to-move.json && \
-		RESULT=$(kafka-reassign-partitions.sh --bootstrap-server "%s" \
-		--topics-to-move-json-file "/tmp/topics-to-move.json" \
-		--broker-list "%s" --generate \
-		--command-config /tmp/config.properties)
-		if [ $? -ne 0 ]; then
-			echo $RESULT >&2
-			rm /tmp/config.properties /tmp/topics-to-move.json
-			exit 1
-		fi && \
-		echo "$RESULT" | awk '
-			/Current partition replica assignment/,/^$/ {
-				if (!/Current partition replica assignment/ && !/^$/) {
-					print > "/tmp/backup-expand-cluster-reassignment.json"
-				}
+	for key, value := range params {
+		if value == nil {
+			continue // ~ или null означает значение брокера по умолчанию
+		}
+		strValue := fmt.Sprintf("%v", value)
+		switch key {
+		case "partitions":
+			partitions, err := strconv.ParseInt(strValue, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("некорректное значение partitions %q: %v", strValue, err)
 			}
-			/Proposed partition reassignment configuration/,0 {
-				if (!/Proposed partition reassignment configuration/) {
-					print > "/tmp/expand-cluster-reassignment.json"
-				}
+			detail.NumPartitions = int32(partitions)
+		case "replicas":
+			replicas, err := strconv.ParseInt(strValue, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("некорректное значение replicas %q: %v", strValue, err)
 			}
-		' && \
-		rm /tmp/config.properties`, config, string(jsonData), broker, brokerListId)
-
-	cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%s", string(output))
+			detail.ReplicationFactor = int16(replicas)
+		case newPartitionAssignmentKey:
+			continue // Используется только при увеличении количества партиций
+		default:
+			detail.ConfigEntries[key] = &strValue
+		}
 	}
-	return nil
+	return detail, nil
 }
 
-func (c *Topic) topicVerifyReassignPart() error {
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0] // Берем первый элемент из среза
-	} else {
-		log.Printf("Список брокеров пуст")
-		return fmt.Errorf("список брокеров пуст")
+func (c *Topic) topicCreate(client sarama.Client, filePath string) error {
+	topicsConfig, err := readTopicsFile(filePath)
+	if err != nil {
+		return err
 	}
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
 
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
 
-	commandStr := fmt.Sprintf(`
-		echo '%s' > /tmp/config.properties && \
-		kafka-reassign-partitions.sh --bootstrap-server "%s" \
-		--reassignment-json-file /tmp/expand-cluster-reassignment.json --verify \
-		--command-config /tmp/config.properties && \
-		rm /tmp/config.properties`, config, broker)
+	// Сортируем имена топиков, чтобы порядок создания и отчет были предсказуемыми
+	topicNames := make([]string, 0, len(topicsConfig))
+	for topicName := range topicsConfig {
+		topicNames = append(topicNames, topicName)
+	}
+	slices.Sort(topicNames)
 
-	// Вывод команды в лог
-	// log.Printf("Executing command: %s", commandStr)
+	// Создаем топики
+	var created []string
+	createErrors := make(map[string]error)
+	for _, topicName := range topicNames {
+		detail, err := topicDetailFromParams(topicsConfig[topicName])
+		if err != nil {
+			log.Printf("Ошибка в параметрах топика %s: %v", topicName, err)
+			createErrors[topicName] = err
+			continue
+		}
 
-	cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
+		if err := admin.CreateTopic(topicName, detail, false); err != nil {
+			switch {
+			case errors.Is(err, sarama.ErrTopicAlreadyExists):
+				log.Printf("Топик %s уже существует", topicName)
+			case errors.Is(err, sarama.ErrInvalidConfig):
+				log.Printf("Некорректная конфигурация топика %s: %v", topicName, err)
+			case errors.Is(err, sarama.ErrPolicyViolation):
+				log.Printf("Создание топика %s запрещено политикой кластера: %v", topicName, err)
+			default:
+				log.Printf("Ошибка создания топика %s: %v", topicName, err)
+			}
+			createErrors[topicName] = err
+			continue
+		}
+		created = append(created, topicName)
+		log.Printf("Топик %s успешно создан (партиций: %d, реплик: %d)", topicName, detail.NumPartitions, detail.ReplicationFactor)
+	}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// Итоговый отчет
+	log.Printf("Создано топиков: %d из %d", len(created), len(topicNames))
+	if len(createErrors) == 0 {
+		return nil
+	}
+	var failed []string
+	for _, topicName := range topicNames {
+		if err, ok := createErrors[topicName]; ok {
+			log.Printf("  ❌ %s: %v", topicName, err)
+			failed = append(failed, topicName)
+		}
+	}
+	return fmt.Errorf("не удалось создать топики: %s", strings.Join(failed, ", "))
+}
 
-	return cmd.Run()
+// Служебные топики Kafka и экосистемы, которые запрещено удалять
+var internalTopics = []string{
+	"__consumer_offsets",
+	"__transaction_state",
+	"__cluster_metadata",
+	"_schemas",
+	"connect-configs",
+	"connect-offsets",
+	"connect-status",
 }
 
-func (c *Topic) topicApplyReassignPart() error {
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0] // Берем первый элемент из среза
-	} else {
-		log.Printf("Список брокеров пуст")
-		return fmt.Errorf("список брокеров пуст")
+// Проверяем, защищен ли топик от удаления: служебные топики, топики с префиксом "__"
+// и топики из списка kafka.protectedTopics в config.yaml (поддерживаются шаблоны вида "prod.*")
+func isProtectedTopic(topicName string) bool {
+	if strings.HasPrefix(topicName, "__") || slices.Contains(internalTopics, topicName) {
+		return true
 	}
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
-
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
-
-	commandStr := fmt.Sprintf(`
-		echo '%s' > /tmp/config.properties && \
-		kafka-reassign-partitions.sh --bootstrap-server "%s" \
-		--reassignment-json-file /tmp/expand-cluster-reassignment.json --execute \
-		--command-config /tmp/config.properties && \
-		rm /tmp/config.properties`, config, broker)
-
-	// Вывод команды в лог
-	// log.Printf("Executing command: %s", commandStr)
-
-	cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	return cmd.Run()
+	for _, pattern := range viper.GetStringSlice("kafka.protectedTopics") {
+		if matched, err := path.Match(pattern, topicName); err == nil && matched {
+			return true
+		}
+	}
+	return false
 }
 
-func (c *Topic) topicRollbackReassignPart() error {
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0] // Берем первый элемент из среза
-	} else {
-		log.Printf("Список брокеров пуст")
-		return fmt.Errorf("список брокеров пуст")
+// При deleteGroups также удаляются группы потребителей без активных участников,
+// которые читали только удаленные топики
+func (c *Topic) topicDelete(client sarama.Client, topics []string, deleteGroups bool) error {
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
 	}
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
+	defer admin.Close()
 
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
+	// Получаем список топиков из kafka для проверки существования
+	existing, err := admin.ListTopics()
+	if err != nil {
+		log.Printf("Ошибка получения списка топиков: %v", err)
+		return err
+	}
 
-	commandStr := fmt.Sprintf(`
-		echo '%s' > /tmp/config.properties && \
-		kafka-reassign-partitions.sh --bootstrap-server "%s" \
-		--reassignment-json-file /tmp/backup-expand-cluster-reassignment.json --execute \
-		--command-config /tmp/config.properties && \
-		rm /tmp/config.properties`, config, broker)
+	// Группы определяются до удаления: после удаления топика Kafka удаляет смещения групп по нему
+	var groups []*sarama.GroupDescription
+	if deleteGroups {
+		if groups, err = groupsConsumingOnly(admin, topics); err != nil {
+			return err
+		}
+	}
 
-	// Вывод команды в лог
-	// log.Printf("Executing command: %s", commandStr)
+	var deleted, missing, protected, failed []string
+	for _, topic := range topics {
+		if isProtectedTopic(topic) {
+			log.Printf("Топик %s защищен от удаления", topic)
+			protected = append(protected, topic)
+			continue
+		}
+		if _, ok := existing[topic]; !ok {
+			log.Printf("Топик %s не найден в Kafka", topic)
+			missing = append(missing, topic)
+			continue
+		}
+		if err := admin.DeleteTopic(topic); err != nil {
+			log.Printf("Ошибка удаления топика %s: %v", topic, err)
+			failed = append(failed, topic)
+			continue
+		}
+		deleted = append(deleted, topic)
+		log.Printf("Топик %s успешно удален", topic)
+	}
+
+	// Итоговый отчет
+	log.Printf("Удалено: %d %v", len(deleted), deleted)
+	log.Printf("Не найдено: %d %v", len(missing), missing)
+	log.Printf("Защищено от удаления: %d %v", len(protected), protected)
+	log.Printf("Ошибки удаления: %d %v", len(failed), failed)
+
+	// Удаляем только группы, все топики которых действительно удалены
+	var groupsFailed []string
+	if deleteGroups {
+		var cleanup []*sarama.GroupDescription
+		for _, group := range groups {
+			committed, err := groupCommittedTopics(admin, group.GroupId)
+			if err != nil {
+				log.Printf("Группа %s: %v", group.GroupId, err)
+				groupsFailed = append(groupsFailed, group.GroupId)
+				continue
+			}
+			if !slices.ContainsFunc(committed, func(topic string) bool { return !slices.Contains(deleted, topic) }) {
+				cleanup = append(cleanup, group)
+			}
+		}
+		groupsDeleted, groupsActive, failedGroups := deleteEmptyGroups(admin, cleanup)
+		groupsFailed = append(groupsFailed, failedGroups...)
+		log.Printf("Удалено групп потребителей: %d %v", len(groupsDeleted), groupsDeleted)
+		if len(groupsActive) > 0 {
+			log.Printf("Группы с активными участниками не удалены: %d %v", len(groupsActive), groupsActive)
+		}
+	}
 
-	cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
+	// Ненайденный топик, указанный явно, тоже считается ошибкой: иначе удаление опечатки выглядит успешным
+	if notDeleted := slices.Concat(missing, protected, failed); len(notDeleted) > 0 {
+		return fmt.Errorf("не удалось удалить топики: %s", strings.Join(notDeleted, ", "))
+	}
+	if len(groupsFailed) > 0 {
+		return fmt.Errorf("не удалось удалить группы потребителей: %s", strings.Join(groupsFailed, ", "))
+	}
+	return nil
+}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// Получаем текущую конфигурацию топика в виде карты имя параметра -> значение
+func describeTopicConfig(admin sarama.ClusterAdmin, topicName string) (map[string]sarama.ConfigEntry, error) {
+	entries, err := admin.DescribeConfig(sarama.ConfigResource{
+		Type: sarama.TopicResource,
+		Name: topicName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]sarama.ConfigEntry, len(entries))
+	for _, entry := range entries {
+		result[entry.Name] = entry
+	}
+	return result, nil
+}
 
-	return cmd.Run()
+// Форматируем значение параметра для вывода, помечая значения по умолчанию
+func formatConfigValue(entry sarama.ConfigEntry, ok bool) string {
+	if !ok {
+		return "<не задан>"
+	}
+	if entry.Sensitive {
+		return "<скрыто>"
+	}
+	if entry.Source != sarama.SourceTopic {
+		return fmt.Sprintf("%s (по умолчанию, %s)", entry.Value, entry.Source)
+	}
+	return entry.Value
 }
 
-func (c *Topic) topicCreate(filePath string) error {
-	// Чтение YAML файла
-	data, err := os.ReadFile(filePath)
+func (c *Topic) topicChange(client sarama.Client, filePath string) error {
+	topicsConfig, err := readTopicsFile(filePath)
 	if err != nil {
-		log.Printf("Ошибка чтения файла %s: %v", filePath, err)
 		return err
 	}
 
-	// Парсинг YAML в карту
-	var values map[string]map[string]map[string]interface{}
-	err = yaml.Unmarshal(data, &values)
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
 	if err != nil {
-		log.Printf("Ошибка парсинга YAML файла: %v", err)
+		log.Printf("Ошибка создания админ-клиента: %v", err)
 		return err
 	}
+	defer admin.Close()
 
-	// Проверка наличия секции "topics"
-	topicsConfig, exists := values["topics"]
-	if !exists {
-		return fmt.Errorf("отсутствует секция 'topics'")
-	}
-
-	// Получаем параметры из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0]
-	} else {
-		return fmt.Errorf("список брокеров пуст")
+	topicNames := make([]string, 0, len(topicsConfig))
+	for topicName := range topicsConfig {
+		topicNames = append(topicNames, topicName)
 	}
+	slices.Sort(topicNames)
 
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
+	// Изменяем топики
+	var failed []string
+	for _, topicName := range topicNames {
+		params := topicsConfig[topicName]
 
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
-
-	// Создаем топики
-	// var createErrors []string
-	for topicName, params := range topicsConfig {
-		var configParams []string
-		var partitions, replicas string
-
-		// Обработка параметров
-		for key, value := range params {
-			strValue := fmt.Sprintf("%v", value)
-			switch key {
-			case "partitions":
-				partitions = strValue
-			case "replicas":
-				replicas = strValue
-			default:
-				configParams = append(configParams, fmt.Sprintf("%s=%s", key, strValue))
-			}
+		before, err := describeTopicConfig(admin, topicName)
+		if err != nil {
+			log.Printf("Ошибка получения конфигурации топика %s: %v", topicName, err)
+			failed = append(failed, topicName)
+			continue
 		}
 
-		// Формируем дополнительные параметры конфигурации идущие через --config
-		var command string
-		if len(configParams) > 0 {
-			for _, param := range configParams {
-				command += fmt.Sprintf(` --config %s`, param)
-			}
+		configChanged, err := alterTopicConfig(admin, topicName, params, before)
+		if err != nil {
+			log.Printf("Ошибка при изменении топика %s: %v", topicName, err)
+			failed = append(failed, topicName)
+			continue
 		}
+		partitionsChanged, err := increaseTopicPartitions(admin, topicName, params, before)
+		if err != nil {
+			log.Printf("Ошибка при изменении количества партиций топика %s: %v", topicName, err)
+			failed = append(failed, topicName)
+			continue
+		}
+		if !configChanged && !partitionsChanged {
+			log.Printf("Топик %s не требует изменений", topicName)
+		}
+	}
 
-		// Формируем команду
-		commandStr := fmt.Sprintf(`
-			echo '%s' > /tmp/config.properties && \
-			kafka-topics.sh --create --topic %s \
-			--bootstrap-server %s \
-			--partitions %s \
-			--replication-factor %s \
-			%s \
-			--command-config /tmp/config.properties && \
-			rm /tmp/config.properties`,
-			config, topicName, broker, partitions, replicas, command)
-
-		// Выполняем команду
-		cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-		cmd.Stdout = os.Stdout
-		// cmd.Stderr = os.Stderr // Выводим только ошибки инструментов кафки
+	if len(failed) > 0 {
+		return fmt.Errorf("не удалось изменить топики: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
 
-		if err := cmd.Run(); err != nil {
-			continue // Продолжаем со следующим топиком
+// Формируем список изменений конфигурации топика: значение ~ или null удаляет параметр
+// с топика (возвращается значение брокера по умолчанию), остальные устанавливаются.
+// Параметры, значения которых не изменились, пропускаются
+func topicConfigChanges(params map[string]interface{}, before map[string]sarama.ConfigEntry) map[string]sarama.IncrementalAlterConfigsEntry {
+	entries := make(map[string]sarama.IncrementalAlterConfigsEntry)
+	for key, value := range params {
+		if isTopicSpecKey(key) {
+			continue
+		}
+		current, ok := before[key]
+		if value == nil {
+			if ok && current.Source != sarama.SourceTopic {
+				continue // Параметр не переопределен на топике, удалять нечего
+			}
+			entries[key] = sarama.IncrementalAlterConfigsEntry{
+				Operation: sarama.IncrementalAlterConfigsOperationDelete,
+			}
+			continue
+		}
+		strValue := fmt.Sprintf("%v", value)
+		if ok && current.Source == sarama.SourceTopic && current.Value == strValue {
+			continue // Значение не изменилось
+		}
+		entries[key] = sarama.IncrementalAlterConfigsEntry{
+			Operation: sarama.IncrementalAlterConfigsOperationSet,
+			Value:     &strValue,
 		}
-		log.Printf("Топик %s успешно создан", topicName)
 	}
-	return nil
+	return entries
 }
 
-func (c *Topic) topicDelete(topicName string, topics []string) error {
-	// Получаем параметры из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0]
-	} else {
-		return fmt.Errorf("список брокеров пуст")
+// Изменяем конфигурацию топика и выводим значения измененных параметров до и после
+func alterTopicConfig(admin sarama.ClusterAdmin, topicName string, params map[string]interface{}, before map[string]sarama.ConfigEntry) (bool, error) {
+	entries := topicConfigChanges(params, before)
+	if len(entries) == 0 {
+		return false, nil
 	}
 
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
+	if err := admin.IncrementalAlterConfig(sarama.TopicResource, topicName, entries, false); err != nil {
+		return false, err
+	}
 
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
+	// Выводим значения до и после изменения
+	after, err := describeTopicConfig(admin, topicName)
+	if err != nil {
+		log.Printf("Ошибка получения конфигурации топика %s после изменения: %v", topicName, err)
+		after = nil
+	}
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	log.Printf("Топик %s успешно обновлен:", topicName)
+	for _, key := range keys {
+		beforeEntry, beforeOk := before[key]
+		afterEntry, afterOk := after[key]
+		log.Printf("  %s: %s -> %s", key, formatConfigValue(beforeEntry, beforeOk), formatConfigValue(afterEntry, afterOk))
+	}
+	return true, nil
+}
 
-	if topicName != "" {
-		// удаляем топик
-		commandStr := fmt.Sprintf(`
-			echo '%s' > /tmp/config.properties && \
-			kafka-topics.sh --delete --topic %s \
-			--bootstrap-server %s \
-			--command-config /tmp/config.properties && \
-			rm /tmp/config.properties`,
-			config, topicName, broker)
-
-		// Выполняем команду
-		cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr // Выводим только ошибки инструментов кафки
-
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("ошибка удаления топика %s: %v", topicName, err)
+// Разбираем явное распределение реплик для новых партиций из YAML вида [[1, 2], [2, 3]]
+func parsePartitionAssignment(value interface{}) ([][]int32, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s должен быть списком списков id брокеров", newPartitionAssignmentKey)
+	}
+	assignment := make([][]int32, 0, len(items))
+	for _, item := range items {
+		replicasList, ok := item.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s должен быть списком списков id брокеров", newPartitionAssignmentKey)
 		}
-		return nil
-	}
-	if len(topics) > 0 {
-		for _, topic := range topics {
-			commandStr := fmt.Sprintf(`
-				echo '%s' > /tmp/config.properties && \
-				kafka-topics.sh --delete --topic %s \
-				--bootstrap-server %s \
-				--command-config /tmp/config.properties && \
-				rm /tmp/config.properties`,
-				config, topic, broker)
-
-			// Выполняем команду
-			cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr // Выводим только ошибки инструментов кафки
-
-			if err := cmd.Run(); err != nil {
-				// log.Printf("ошибка удаления топика %s: %v", topic, err)
-				continue
+		var replicas []int32
+		for _, replica := range replicasList {
+			id, err := strconv.ParseInt(fmt.Sprintf("%v", replica), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("некорректный id брокера %v в %s", replica, newPartitionAssignmentKey)
 			}
-			log.Printf("Топик %s успешно удален", topic)
+			replicas = append(replicas, int32(id))
 		}
+		assignment = append(assignment, replicas)
 	}
-	return nil
+	return assignment, nil
 }
 
-func (c *Topic) topicChange(filePath string) error {
-	// Чтение YAML файла
-	data, err := os.ReadFile(filePath)
+// Текущее количество партиций топика
+func topicPartitionCount(admin sarama.ClusterAdmin, topicName string) (int32, error) {
+	metadata, err := admin.DescribeTopics([]string{topicName})
 	if err != nil {
-		log.Printf("Ошибка чтения файла %s: %v", filePath, err)
-		return err
+		return 0, err
+	}
+	if len(metadata) == 0 {
+		return 0, fmt.Errorf("топик %s не найден", topicName)
+	}
+	if metadata[0].Err != sarama.ErrNoError {
+		return 0, metadata[0].Err
 	}
+	return int32(len(metadata[0].Partitions)), nil
+}
 
-	// Парсинг YAML в карту
-	var values map[string]map[string]map[string]interface{}
-	err = yaml.Unmarshal(data, &values)
+// Увеличиваем количество партиций топика до значения partitions из YAML.
+// Уменьшение количества партиций Kafka не поддерживает, поэтому такой запрос отклоняется
+func increaseTopicPartitions(admin sarama.ClusterAdmin, topicName string, params map[string]interface{}, config map[string]sarama.ConfigEntry) (bool, error) {
+	value, ok := params["partitions"]
+	if !ok || value == nil {
+		return false, nil
+	}
+	desired, err := strconv.ParseInt(fmt.Sprintf("%v", value), 10, 32)
 	if err != nil {
-		log.Printf("Ошибка парсинга YAML файла: %v", err)
-		return err
+		return false, fmt.Errorf("некорректное значение partitions %v: %v", value, err)
 	}
 
-	// Проверка наличия секции "topics"
-	topicsConfig, exists := values["topics"]
-	if !exists {
-		return fmt.Errorf("отсутствует секция 'topics'")
+	current, err := topicPartitionCount(admin, topicName)
+	if err != nil {
+		return false, err
 	}
-
-	// Получаем параметры из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0]
-	} else {
-		return fmt.Errorf("список брокеров пуст")
+	if int32(desired) == current {
+		return false, nil
+	}
+	if int32(desired) < current {
+		return false, fmt.Errorf("уменьшение количества партиций с %d до %d не поддерживается Kafka", current, desired)
 	}
 
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslUsername := viper.GetString("kafka.sasl.username")
-	saslPassword := viper.GetString("kafka.sasl.password")
-
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslUsername, saslPassword)
-
-	// Создаем топики
-	// var createErrors []string
-	for topicName, params := range topicsConfig {
-		var configParams []string
-		// var partitions, replicas string
-
-		// Обработка параметров
-		for key, value := range params {
-			strValue := fmt.Sprintf("%v", value)
-			if key == "partitions" || key == "replicas" {
-				continue
-			}
-			configParams = append(configParams, fmt.Sprintf("%s=%s", key, strValue))
+	var assignment [][]int32
+	if value, ok := params[newPartitionAssignmentKey]; ok && value != nil {
+		assignment, err = parsePartitionAssignment(value)
+		if err != nil {
+			return false, err
 		}
-
-		// Формируем дополнительные параметры конфигурации идущие через --add-config
-		var command string
-		if len(configParams) > 0 {
-			for _, param := range configParams {
-				command += fmt.Sprintf("%s,", param)
-			}
+		if len(assignment) != int(int32(desired)-current) {
+			return false, fmt.Errorf("%s содержит %d партиций, а добавляется %d", newPartitionAssignmentKey, len(assignment), int32(desired)-current)
 		}
+	}
 
-		// Формируем команду
-		commandStr := fmt.Sprintf(`
-			echo '%s' > /tmp/config.properties && \
-			kafka-configs.sh --bootstrap-server %s \
-			--entity-type topics \
-			--entity-name %s \
-			--alter \
-			--add-config %s \
-			--command-config /tmp/config.properties && \
-			rm /tmp/config.properties`,
-			config, broker, topicName, command)
-
-		// Выполняем команду
-		cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr // Выводим только ошибки инструментов кафки
+	// Для сообщений с ключом меняется соответствие ключа и партиции
+	if strings.Contains(config["cleanup.policy"].Value, "compact") {
+		log.Printf("⚠️ Топик %s сжимаемый (cleanup.policy=%s): после увеличения партиций новые значения ключей попадут в другие партиции, и сжатие не удалит старые значения",
+			topicName, config["cleanup.policy"].Value)
+	} else {
+		log.Printf("⚠️ Топик %s: после увеличения партиций сообщения с ключом могут попадать в другие партиции, порядок сообщений по ключу не гарантируется", topicName)
+	}
 
-		if err := cmd.Run(); err != nil {
-			// log.Printf("Ошибка при изменении топика %s: %v", topicName, err)
-			continue // Продолжаем со следующим топиком
-		}
-		log.Printf("Топик %s успешно обновлен", topicName)
+	if err := admin.CreatePartitions(topicName, int32(desired), assignment, false); err != nil {
+		return false, err
 	}
-	return nil
+	log.Printf("Топик %s: количество партиций увеличено с %d до %d", topicName, current, desired)
+	return true, nil
 }
 
 type Broker struct{}
 
-// func (b *Broker) brokerList(client sarama.Client) ([]int32, error) {
-// 	// Получаем список всех брокеров из кластера
-// 	brokers := client.Brokers()
-// 	if len(brokers) == 0 {
-// 		return []int32{}, fmt.Errorf("не найдено активных брокеров")
-// 	}
-
-// 	// Получаем ID каждого брокера
-// 	var brokerIDs []int32
-// 	for _, broker := range brokers {
-// 		brokerIDs = append(brokerIDs, broker.ID())
-// 	}
-// 	return brokerIDs, nil
-// }
-
-type Acl struct{}
+func (b *Broker) brokerList(client sarama.Client) ([]int32, error) {
+	// Получаем список всех брокеров из кластера
+	brokers := client.Brokers()
+	if len(brokers) == 0 {
+		return []int32{}, fmt.Errorf("не найдено активных брокеров")
+	}
 
-func (a *Acl) aclList(principal string) error {
-	// Получаем параметры из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0]
-	} else {
-		return fmt.Errorf("список брокеров пуст")
+	// Получаем ID каждого брокера
+	var brokerIDs []int32
+	for _, broker := range brokers {
+		brokerIDs = append(brokerIDs, broker.ID())
 	}
+	slices.Sort(brokerIDs)
+	return brokerIDs, nil
+}
 
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslAdminUsername := viper.GetString("kafka.sasl.username")
-	saslAdminPassword := viper.GetString("kafka.sasl.password")
+type Acl struct{}
 
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslAdminUsername, saslAdminPassword)
+// Описание ACL пользователя в YAML файле
+type aclConfig struct {
+	Allow               bool   `yaml:"allow"`
+	Operation           string `yaml:"operation"`
+	Host                string `yaml:"host,omitempty"`
+	Topic               string `yaml:"topic,omitempty"`
+	Group               string `yaml:"group,omitempty"`
+	Cluster             string `yaml:"cluster,omitempty"`
+	TransactionalID     string `yaml:"transactional-id,omitempty"`
+	DelegationToken     string `yaml:"delegation-token,omitempty"`
+	ResourcePatternType string `yaml:"resource-pattern-type"`
+}
 
-	// Базовая часть команды
-	baseCommand := fmt.Sprintf(`
-		echo '%s' > /tmp/config.properties && \
-		echo "===== Список всех ACL =====" && \
-		kafka-acls.sh --bootstrap-server %s \
-		--command-config /tmp/config.properties \
-		--list`, config, broker)
+// Преобразуем ACL из YAML в ресурс и ACL sarama для указанного принципала
+func (a aclConfig) toSarama(principal string) (sarama.Resource, sarama.Acl, error) {
+	var resource sarama.Resource
+	var acl sarama.Acl
+
+	// Определяем тип и имя ресурса
+	switch {
+	case a.Topic != "":
+		resource.ResourceType, resource.ResourceName = sarama.AclResourceTopic, a.Topic
+	case a.Group != "":
+		resource.ResourceType, resource.ResourceName = sarama.AclResourceGroup, a.Group
+	case a.Cluster != "":
+		resource.ResourceType, resource.ResourceName = sarama.AclResourceCluster, a.Cluster
+	case a.TransactionalID != "":
+		resource.ResourceType, resource.ResourceName = sarama.AclResourceTransactionalID, a.TransactionalID
+	case a.DelegationToken != "":
+		resource.ResourceType, resource.ResourceName = sarama.AclResourceDelegationToken, a.DelegationToken
+	default:
+		return resource, acl, fmt.Errorf("не указан ресурс")
+	}
+
+	// Определяем тип шаблона ресурса (по умолчанию literal)
+	patternType := a.ResourcePatternType
+	if patternType == "" {
+		patternType = "literal"
+	}
+	if err := resource.ResourcePatternType.UnmarshalText([]byte(patternType)); err != nil {
+		return resource, acl, fmt.Errorf("неизвестный тип шаблона ресурса %q", a.ResourcePatternType)
+	}
+	switch resource.ResourcePatternType {
+	case sarama.AclPatternLiteral, sarama.AclPatternPrefixed:
+	default:
+		// match и any применимы только для фильтров, ACL с ними создать нельзя
+		return resource, acl, fmt.Errorf("тип шаблона %q допустим только для поиска ACL, для создания используйте literal или prefixed", a.ResourcePatternType)
+	}
+
+	// Определяем операцию, допускаются варианты вида describe-configs и DescribeConfigs
+	operation := strings.NewReplacer("-", "", "_", "").Replace(a.Operation)
+	// unknown и any применимы только для фильтров, брокер отклоняет ACL с ними вместе со всем запросом
+	if err := acl.Operation.UnmarshalText([]byte(operation)); err != nil ||
+		acl.Operation == sarama.AclOperationUnknown || acl.Operation == sarama.AclOperationAny {
+		return resource, acl, fmt.Errorf("неизвестная операция %q: должна быть all, read, write, create, delete, alter, describe, cluster-action, describe-configs, alter-configs или idempotent-write", a.Operation)
+	}
+
+	// Определяем тип доступа (allow/deny)
+	acl.PermissionType = sarama.AclPermissionAllow
+	if !a.Allow {
+		acl.PermissionType = sarama.AclPermissionDeny
+	}
+
+	acl.Principal = principal
+	acl.Host = a.Host
+	if acl.Host == "" {
+		acl.Host = "*"
+	}
+	return resource, acl, nil
+}
 
-	principalFilter := ""
-	if principal != "" {
-		principalFilter = fmt.Sprintf(` --principal "User:%s"`, principal)
+// Приводим имя пользователя к принципалу Kafka, если префикс не указан явно
+func userPrincipal(name string) string {
+	if strings.Contains(name, ":") {
+		return name
 	}
+	return "User:" + name
+}
 
-	// Команда для отображения всех ACL без фильтров по типу ресурса
-	commandStr := fmt.Sprintf(`%s%s`, baseCommand, principalFilter)
-
-	// Завершаем команду
-	commandStr += ` && rm /tmp/config.properties`
+// Получаем список ACL, "*" или пустая строка - ACL всех принципалов
+func listAcls(admin sarama.ClusterAdmin, principal string) ([]sarama.ResourceAcls, error) {
+	filter := sarama.AclFilter{
+		ResourceType:              sarama.AclResourceAny,
+		ResourcePatternTypeFilter: sarama.AclPatternAny,
+		Operation:                 sarama.AclOperationAny,
+		PermissionType:            sarama.AclPermissionAny,
+	}
+	if principal != "" && principal != "*" {
+		p := userPrincipal(principal)
+		filter.Principal = &p
+	}
+	return admin.ListAcls(filter)
+}
 
-	// Выполняем команду
-	cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+func (a *Acl) aclList(client sarama.Client, principal string) error {
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
 
-	err := cmd.Run()
+	resourceAcls, err := listAcls(admin, principal)
 	if err != nil {
 		return fmt.Errorf("ошибка получения списка ACL: %v", err)
 	}
 
+	// Разворачиваем ACL в строки таблицы
+	var rows [][]string
+	for _, resourceAcl := range resourceAcls {
+		for _, acl := range resourceAcl.Acls {
+			rows = append(rows, []string{
+				acl.Principal,
+				acl.PermissionType.String(),
+				acl.Operation.String(),
+				acl.Host,
+				resourceAcl.ResourceType.String(),
+				resourceAcl.ResourcePatternType.String(),
+				resourceAcl.ResourceName,
+			})
+		}
+	}
+	slices.SortFunc(rows, func(x, y []string) int {
+		return slices.Compare(x, y)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRINCIPAL\tPERMISSION\tOPERATION\tHOST\tRESOURCE TYPE\tPATTERN\tRESOURCE")
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Найдено ACL: %d", len(rows))
 	return nil
 }
 
 type User struct{}
 
-func (u *User) userCreate(filePath string) error {
-	// Получаем параметры из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0]
-	} else {
-		return fmt.Errorf("список брокеров пуст")
-	}
-
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslAdminUsername := viper.GetString("kafka.sasl.username")
-	saslAdminPassword := viper.GetString("kafka.sasl.password")
-
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslAdminUsername, saslAdminPassword)
-
-	// Чтение данных пользователей из YAML-файла
-	type User struct {
-		Username string `yaml:"username"`
-		Password string `yaml:"password"`
-	}
+// Описание пользователя в YAML файле
+type userConfig struct {
+	Username         string      `yaml:"username"`
+	Password         string      `yaml:"password,omitempty"`
+	GeneratePassword bool        `yaml:"generatePassword,omitempty"`
+	Mechanisms       []string    `yaml:"mechanisms,omitempty"`
+	Iterations       int32       `yaml:"iterations,omitempty"`
+	Acls             []aclConfig `yaml:"acls,omitempty"`
+}
 
+// Читаем YAML файл с пользователями
+func readUsersFile(filePath string) ([]userConfig, error) {
 	type UsersFile struct {
-		Users []User `yaml:"users"`
+		Users []userConfig `yaml:"users"`
 	}
 
 	// Чтение YAML файла
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("ошибка чтения файла %s: %v", filePath, err)
+		return nil, fmt.Errorf("ошибка чтения файла %s: %v", filePath, err)
 	}
 
 	// Парсинг YAML в структуру
 	var usersFile UsersFile
 	err = yaml.Unmarshal(data, &usersFile)
 	if err != nil {
-		return fmt.Errorf("ошибка парсинга YAML файла: %v", err)
+		return nil, fmt.Errorf("ошибка парсинга YAML файла: %v", err)
 	}
 
 	// Проверка наличия пользователей
 	if len(usersFile.Users) == 0 {
-		return fmt.Errorf("не найдено пользователей в файле")
+		return nil, fmt.Errorf("не найдено пользователей в файле")
 	}
+	return usersFile.Users, nil
+}
 
-	// Создаем пользователей из файла
-	for _, user := range usersFile.Users {
-		if user.Username == "" || user.Password == "" {
-			log.Printf("Пропуск пользователя из-за отсутствия имени или пароля")
+// Количество итераций SCRAM по умолчанию и допустимые границы (ограничения брокера Kafka)
+const (
+	defaultScramIterations = 4096
+	minScramIterations     = 4096
+	maxScramIterations     = 16384
+)
+
+// Код ошибки RESOURCE_NOT_FOUND, которого нет среди констант sarama.
+// Возвращается брокером при запросе учетных данных несуществующего пользователя
+const errResourceNotFound sarama.KError = 91
+
+// Преобразуем имя механизма из YAML в тип sarama
+func scramMechanism(name string) (sarama.ScramMechanismType, error) {
+	switch strings.ToUpper(name) {
+	case "SCRAM-SHA-256":
+		return sarama.SCRAM_MECHANISM_SHA_256, nil
+	case "SCRAM-SHA-512":
+		return sarama.SCRAM_MECHANISM_SHA_512, nil
+	}
+	return sarama.SCRAM_MECHANISM_UNKNOWN, fmt.Errorf("неподдерживаемый механизм %q: должен быть \"SCRAM-SHA-256\" или \"SCRAM-SHA-512\"", name)
+}
+
+// Получаем механизмы SCRAM, которые уже настроены у пользователя
+func describeUserScram(admin sarama.ClusterAdmin, username string) ([]sarama.ScramMechanismType, error) {
+	results, err := admin.DescribeUserScramCredentials([]string{username})
+	if err != nil {
+		return nil, err
+	}
+	var mechanisms []sarama.ScramMechanismType
+	for _, result := range results {
+		if result.User != username {
 			continue
 		}
+		switch result.ErrorCode {
+		case sarama.ErrNoError:
+		case errResourceNotFound:
+			return nil, nil
+		default:
+			return nil, result.ErrorCode
+		}
+		for _, info := range result.CredentialInfos {
+			mechanisms = append(mechanisms, info.Mechanism)
+		}
+	}
+	return mechanisms, nil
+}
 
-		// Формируем команду для создания пользователя
-		commandStr := fmt.Sprintf(`
-			echo '%s' > /tmp/config.properties && \
-			kafka-configs.sh --bootstrap-server %s \
-			--command-config /tmp/config.properties \
-			--alter --add-config 'SCRAM-SHA-512=[password=%s]' \
-			--entity-type users --entity-name %s && \
-			rm /tmp/config.properties`,
-			config, broker, user.Password, user.Username)
+func (u *User) userCreate(client sarama.Client, filePath, passwordFile string) error {
+	users, err := readUsersFile(filePath)
+	if err != nil {
+		return err
+	}
+	var generatePasswords bool
+	for _, user := range users {
+		if user.GeneratePassword && passwordFile == "" {
+			return fmt.Errorf("для пользователя %s указан generatePassword, необходимо указать файл для паролей --passwordFile", user.Username)
+		}
+		generatePasswords = generatePasswords || user.GeneratePassword
+	}
+	// Проверяем запись в файл паролей до создания пользователей, иначе сгенерированные пароли будут потеряны
+	if generatePasswords {
+		if err := writePasswordsFile(passwordFile, nil); err != nil {
+			return fmt.Errorf("файл для паролей недоступен: %v", err)
+		}
+	}
 
-		// Выполняем команду
-		cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
 
-		if err := cmd.Run(); err != nil {
-			log.Printf("Ошибка создания пользователя %s: %v", user.Username, err)
-			continue // Продолжаем со следующим пользователем
+	// Создаем или обновляем пользователей из файла
+	var failed []string
+	var generated int
+	for _, user := range users {
+		if user.Username == "" || (user.Password == "" && !user.GeneratePassword) {
+			log.Printf("Пропуск пользователя из-за отсутствия имени или пароля")
+			continue
+		}
+		if user.Password != "" && user.GeneratePassword {
+			log.Printf("Пользователь %s: указаны одновременно password и generatePassword", user.Username)
+			failed = append(failed, user.Username)
+			continue
 		}
 
-		log.Printf("Пользователь %s успешно создан", user.Username)
-	}
+		mechanismNames := user.Mechanisms
+		if len(mechanismNames) == 0 {
+			mechanismNames = []string{"SCRAM-SHA-512"}
+		}
+		iterations := user.Iterations
+		if iterations == 0 {
+			iterations = defaultScramIterations
+		}
+		if iterations < minScramIterations || iterations > maxScramIterations {
+			log.Printf("Пользователь %s: количество итераций %d вне допустимого диапазона %d-%d", user.Username, iterations, minScramIterations, maxScramIterations)
+			failed = append(failed, user.Username)
+			continue
+		}
 
-	return nil
-}
+		// Проверяем, существует ли пользователь, чтобы сообщить о создании или обновлении
+		existing, err := describeUserScram(admin, user.Username)
+		if err != nil {
+			log.Printf("Ошибка получения учетных данных пользователя %s: %v", user.Username, err)
+			failed = append(failed, user.Username)
+			continue
+		}
 
-func (u *User) userAddAcl(filePath string) error {
-	// Получаем параметры из конфига
-	brokerSlice := viper.GetStringSlice("kafka.broker")
-	var broker string
-	if len(brokerSlice) > 0 {
-		broker = brokerSlice[0]
-	} else {
-		return fmt.Errorf("список брокеров пуст")
-	}
+		// Пароль из ссылки на секрет или сгенерированный пароль для нового пользователя
+		password := user.Password
+		if user.GeneratePassword {
+			if len(existing) > 0 {
+				log.Printf("Пользователь %s уже существует, сгенерированный пароль не изменяется", user.Username)
+				continue
+			}
+			password, err = generatePassword()
+		} else {
+			password, err = resolveSecret(password)
+		}
+		if err != nil {
+			log.Printf("Пользователь %s: %v", user.Username, err)
+			failed = append(failed, user.Username)
+			continue
+		}
 
-	securityProtocol := viper.GetString("kafka.sasl.securityProtocol")
-	saslMechanism := viper.GetString("kafka.sasl.mechanism")
-	saslAdminUsername := viper.GetString("kafka.sasl.username")
-	saslAdminPassword := viper.GetString("kafka.sasl.password")
+		// Формируем учетные данные для каждого механизма со своей случайной солью
+		var upserts []sarama.AlterUserScramCredentialsUpsert
+		var mechanismErr error
+		for _, name := range mechanismNames {
+			mechanism, err := scramMechanism(name)
+			if err != nil {
+				mechanismErr = err
+				break
+			}
+			salt := make([]byte, 32)
+			if _, err := rand.Read(salt); err != nil {
+				mechanismErr = fmt.Errorf("ошибка генерации соли: %v", err)
+				break
+			}
+			upserts = append(upserts, sarama.AlterUserScramCredentialsUpsert{
+				Name:       user.Username,
+				Mechanism:  mechanism,
+				Iterations: iterations,
+				Salt:       salt,
+				Password:   []byte(password),
+			})
+		}
+		if mechanismErr != nil {
+			log.Printf("Пользователь %s: %v", user.Username, mechanismErr)
+			failed = append(failed, user.Username)
+			continue
+		}
 
-	config := fmt.Sprintf(`
-	security.protocol=%s
-	sasl.mechanism=%s
-	sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, securityProtocol, saslMechanism, saslAdminUsername, saslAdminPassword)
+		results, err := admin.UpsertUserScramCredentials(upserts)
+		if err != nil {
+			log.Printf("Ошибка создания пользователя %s: %v", user.Username, err)
+			failed = append(failed, user.Username)
+			continue
+		}
+		var resultErr error
+		for _, result := range results {
+			if result.ErrorCode != sarama.ErrNoError {
+				resultErr = result.ErrorCode
+				if result.ErrorMessage != nil {
+					resultErr = fmt.Errorf("%v: %s", result.ErrorCode, *result.ErrorMessage)
+				}
+			}
+		}
+		if resultErr != nil {
+			log.Printf("Ошибка создания пользователя %s: %v", user.Username, resultErr)
+			failed = append(failed, user.Username)
+			continue
+		}
 
-	// Структуры для YAML файла
-	type Acl struct {
-		Allow               bool   `yaml:"allow"`
-		Operation           string `yaml:"operation"`
-		Topic               string `yaml:"topic,omitempty"`
-		Group               string `yaml:"group,omitempty"`
-		Cluster             string `yaml:"cluster,omitempty"`
-		ResourcePatternType string `yaml:"resource-pattern-type"`
+		if len(existing) > 0 {
+			log.Printf("Пользователь %s успешно обновлен (%s, итераций: %d)", user.Username, strings.Join(mechanismNames, ", "), iterations)
+		} else {
+			log.Printf("Пользователь %s успешно создан (%s, итераций: %d)", user.Username, strings.Join(mechanismNames, ", "), iterations)
+		}
+		// Пароль сохраняется сразу после создания пользователя
+		if user.GeneratePassword {
+			if err := writePasswordsFile(passwordFile, map[string]string{user.Username: password}); err != nil {
+				return fmt.Errorf("пароль созданного пользователя %s не сохранен: %v", user.Username, err)
+			}
+			generated++
+		}
 	}
 
-	type User struct {
-		Username string `yaml:"username"`
-		Password string `yaml:"password"`
-		Acls     []Acl  `yaml:"acls,omitempty"`
+	if generated > 0 {
+		log.Printf("Сгенерированные пароли пользователей (%d) сохранены в %s", generated, passwordFile)
 	}
 
-	type UsersFile struct {
-		Users []User `yaml:"users"`
+	if len(failed) > 0 {
+		return fmt.Errorf("не удалось создать пользователей: %s", strings.Join(failed, ", "))
 	}
+	return nil
+}
 
-	// Чтение YAML файла
-	data, err := os.ReadFile(filePath)
+func (u *User) userAddAcl(client sarama.Client, filePath string) error {
+	users, err := readUsersFile(filePath)
 	if err != nil {
-		return fmt.Errorf("ошибка чтения файла %s: %v", filePath, err)
+		return err
 	}
 
-	// Парсинг YAML в структуру
-	var usersFile UsersFile
-	err = yaml.Unmarshal(data, &usersFile)
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
 	if err != nil {
-		return fmt.Errorf("ошибка парсинга YAML файла: %v", err)
-	}
-
-	// Проверка наличия пользователей
-	if len(usersFile.Users) == 0 {
-		return fmt.Errorf("не найдено пользователей в файле")
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
 	}
+	defer admin.Close()
 
 	// Обработка ACL правил для каждого пользователя
-	for _, user := range usersFile.Users {
+	var failed []string
+	for _, user := range users {
 		if user.Username == "" {
 			log.Printf("Пропуск пользователя из-за отсутствия имени")
 			continue
 		}
 
-		principal := fmt.Sprintf("User:%s", user.Username)
+		principal := userPrincipal(user.Username)
 
 		// Обрабатываем все ACL для текущего пользователя
-		for _, acl := range user.Acls {
-			// Определяем тип операции (по умолчанию все в верхнем регистре)
-			operation := strings.ToUpper(acl.Operation)
-
-			// Определяем тип доступа (allow/deny)
-			accessType := "allow"
-			if !acl.Allow {
-				accessType = "deny"
-			}
-
-			// Определяем тип шаблона ресурса
-			resourcePatternType := strings.ToLower(acl.ResourcePatternType)
-
-			// Формируем команду в зависимости от типа ресурса
-			var resourceType, resourceName string
-			if acl.Topic != "" {
-				resourceType = "topic"
-				resourceName = acl.Topic
-			} else if acl.Group != "" {
-				resourceType = "group"
-				resourceName = acl.Group
-			} else if acl.Cluster != "" {
-				resourceType = "cluster"
-				resourceName = acl.Cluster
-			} else {
-				log.Printf("Пропуск ACL: не указан ресурс")
+		var creations []*sarama.AclCreation
+		for _, aclCfg := range user.Acls {
+			resource, acl, err := aclCfg.toSarama(principal)
+			if err != nil {
+				log.Printf("Пропуск ACL пользователя %s: %v", user.Username, err)
+				failed = append(failed, user.Username)
 				continue
 			}
+			creations = append(creations, &sarama.AclCreation{Resource: resource, Acl: acl})
+		}
+		if len(creations) == 0 {
+			continue
+		}
 
-			// Формируем команду для добавления ACL
-			commandStr := fmt.Sprintf(`
-				echo '%s' > /tmp/config.properties && \
-				kafka-acls.sh --bootstrap-server %s \
-				--command-config /tmp/config.properties \
-				--add \
-				--%s-principal "%s" \
-				--operation %s \
-				--resource-pattern-type %s \
-				--%s "%s" && \
-				rm /tmp/config.properties`,
-				config, broker, accessType, principal, operation, resourcePatternType, resourceType, resourceName)
-
-			// Выполняем команду
-			cmd := exec.Command("docker", "exec", "kafka", "bash", "-c", commandStr)
-			output, err := cmd.CombinedOutput()
-			if err != nil {
-				log.Printf("Ошибка добавления ACL для пользователя %s: %v\n%s", user.Username, err, string(output))
-				continue // Продолжаем со следующим ACL
+		errs, err := createAcls(admin, creations)
+		if err != nil {
+			log.Printf("Ошибка добавления ACL для пользователя %s: %v", user.Username, err)
+			failed = append(failed, user.Username)
+			continue
+		}
+		for i, creation := range creations {
+			if errs[i] != nil {
+				log.Printf("Ошибка добавления ACL для пользователя %s: %s %s на %s:%s: %v",
+					user.Username, creation.Operation.String(), creation.PermissionType.String(),
+					creation.ResourceType.String(), creation.ResourceName, errs[i])
+				failed = append(failed, user.Username)
+				continue
 			}
-
-			log.Printf("ACL успешно добавлен для пользователя %s: %s %s на %s:%s",
-				user.Username, operation, accessType, resourceType, resourceName)
+			log.Printf("ACL успешно добавлен для пользователя %s: %s %s на %s:%s (%s, host %s)",
+				user.Username, creation.Operation.String(), creation.PermissionType.String(),
+				creation.ResourceType.String(), creation.ResourceName,
+				creation.ResourcePatternType.String(), creation.Host)
 		}
 	}
 
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return fmt.Errorf("не удалось добавить ACL для пользователей: %s", strings.Join(slices.Compact(failed), ", "))
+	}
 	return nil
 }
 
+// Создаем ACL и возвращаем ошибку для каждого из них.
+// ClusterAdmin.CreateACLs отбрасывает ответ брокера с ошибками по отдельным ACL,
+// поэтому запрос отправляется контроллеру напрямую в том же формате
+func createAcls(admin sarama.ClusterAdmin, creations []*sarama.AclCreation) ([]error, error) {
+	controller, err := admin.Controller()
+	if err != nil {
+		return nil, err
+	}
+	response, err := controller.CreateAcls(&sarama.CreateAclsRequest{
+		Version:      1,
+		AclCreations: creations,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(creations))
+	for i, result := range response.AclCreationResponses {
+		if i >= len(errs) || result.Err == sarama.ErrNoError {
+			continue
+		}
+		errs[i] = result.Err
+		if result.ErrMsg != nil {
+			errs[i] = fmt.Errorf("%v: %s", result.Err, *result.ErrMsg)
+		}
+	}
+	return errs, nil
+}
+
 // Фасад для команд Kafka
 type CommandsKafka struct {
 	topic  *Topic
 	acl    *Acl
 	broker *Broker
 	user   *User
+	group  *Group
 }
 
 // Конструктор фасада
@@ -1030,6 +1157,7 @@ func NewCommandKafka() *CommandsKafka {
 		acl:    &Acl{},
 		broker: &Broker{},
 		user:   &User{},
+		group:  &Group{},
 	}
 }
 
@@ -1055,7 +1183,7 @@ func (c *CommandsKafka) TopicGenerateReassignPart(client sarama.Client, topicsFi
 			}
 		}
 
-		if err := c.topic.topicGenerateReassignPart(topicList); err != nil {
+		if err := c.topic.topicGenerateReassignPart(client, topicList); err != nil {
 			return err
 		}
 	} else {
@@ -1064,7 +1192,7 @@ func (c *CommandsKafka) TopicGenerateReassignPart(client sarama.Client, topicsFi
 		if err != nil {
 			return err
 		}
-		if err := c.topic.topicGenerateReassignPart(topicList); err != nil {
+		if err := c.topic.topicGenerateReassignPart(client, topicList); err != nil {
 			return err
 		}
 	}
@@ -1072,42 +1200,43 @@ func (c *CommandsKafka) TopicGenerateReassignPart(client sarama.Client, topicsFi
 	return nil
 }
 
-func (c *CommandsKafka) TopicVerifyReassignPart() error {
-	if err := c.topic.topicVerifyReassignPart(); err != nil {
+func (c *CommandsKafka) TopicVerifyReassignPart(client sarama.Client) error {
+	if err := c.topic.topicVerifyReassignPart(client); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *CommandsKafka) TopicApplyReassignPart() error {
-	if err := c.topic.topicApplyReassignPart(); err != nil {
+func (c *CommandsKafka) TopicApplyReassignPart(client sarama.Client) error {
+	if err := c.topic.topicApplyReassignPart(client); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *CommandsKafka) TopicRollbackReassignPart() error {
-	if err := c.topic.topicRollbackReassignPart(); err != nil {
+func (c *CommandsKafka) TopicRollbackReassignPart(client sarama.Client) error {
+	if err := c.topic.topicRollbackReassignPart(client); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *CommandsKafka) TopicCreate(filePath string) error {
-	if err := c.topic.topicCreate(filePath); err != nil {
+func (c *CommandsKafka) TopicCreate(client sarama.Client, filePath string) error {
+	if err := c.topic.topicCreate(client, filePath); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *CommandsKafka) TopicChange(filePath string) error {
-	if err := c.topic.topicChange(filePath); err != nil {
+func (c *CommandsKafka) TopicChange(client sarama.Client, filePath string) error {
+	if err := c.topic.topicChange(client, filePath); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *CommandsKafka) TopicDelete(topicName, topicFile string) error {
+func (c *CommandsKafka) TopicDelete(client sarama.Client, topicName, topicFile string, deleteGroups bool) error {
+	var topics []string
 	if topicFile != "" {
 		// Читаем топики из файла
 		content, err := os.ReadFile(topicFile)
@@ -1116,56 +1245,191 @@ func (c *CommandsKafka) TopicDelete(topicName, topicFile string) error {
 		}
 		// Разбиваем на строки и фильтруем пустые
 		lines := strings.Split(string(content), "\n")
-		var topics []string
 		for _, line := range lines {
 			trimmed := strings.TrimSpace(line)
 			if trimmed != "" {
 				topics = append(topics, trimmed)
 			}
 		}
-		if err := c.topic.topicDelete(topicName, topics); err != nil {
-			return err
-		}
 	}
 	if topicName != "" {
-		if err := c.topic.topicDelete(topicName, nil); err != nil {
-			return err
+		topics = append(topics, topicName)
+	}
+	if len(topics) == 0 {
+		return fmt.Errorf("не указаны топики для удаления")
+	}
+	if err := c.topic.topicDelete(client, topics, deleteGroups); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CommandsKafka) UserCreate(client sarama.Client, filePath, passwordFile string) error {
+	if err := c.user.userCreate(client, filePath, passwordFile); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CommandsKafka) UserAddAcl(client sarama.Client, filePath string) error {
+	if err := c.user.userAddAcl(client, filePath); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CommandsKafka) UserReconcileAcl(client sarama.Client, filePath string, prune bool) error {
+	if err := c.user.userReconcileAcl(client, filePath, prune); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Выгружаем топики и пользователей кластера в YAML файлы директории dir.
+// topicRegex ограничивает выгружаемые топики, principal - пользователей
+func (c *CommandsKafka) Export(client sarama.Client, dir, topicRegex, principal string) error {
+	var topicFilter *regexp.Regexp
+	if topicRegex != "" {
+		var err error
+		topicFilter, err = regexp.Compile(topicRegex)
+		if err != nil {
+			return fmt.Errorf("некорректное регулярное выражение %q: %v", topicRegex, err)
 		}
 	}
+	if err := c.topic.topicExport(client, dir, topicFilter); err != nil {
+		return err
+	}
+	if err := c.user.userExport(client, dir, principal); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CommandsKafka) AclList(client sarama.Client, principal string) error {
+	if err := c.acl.aclList(client, principal); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CommandsKafka) TopicPlan(client sarama.Client, filePaths []string) error {
+	if err := c.topic.topicPlan(client, filePaths); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CommandsKafka) TopicApply(client sarama.Client, filePaths []string) error {
+	brokerIDs, err := c.broker.brokerList(client)
+	if err != nil {
+		return err
+	}
+	if err := c.topic.topicApply(client, filePaths, brokerIDs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CommandsKafka) TopicChangeReplicationFactor(client sarama.Client, filePath string) error {
+	brokerIDs, err := c.broker.brokerList(client)
+	if err != nil {
+		return err
+	}
+
+	replicationFactors, freeReplicaBrokerId, replicaBrokerId, err := c.topic.whoTopicPart(client, filePath, brokerIDs)
+	if err != nil {
+		return err
+	}
+	if err := c.topic.topicChangeReplicationFactor(client, replicationFactors, freeReplicaBrokerId, replicaBrokerId); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Выводим параметры клиента Kafka для текущего профиля конфигурации
+func (c *CommandsKafka) PrintClientConfig(output string) error {
+	if err := printClientConfig(output); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Выполняем CLI инструмент Kafka (kafka-*.sh) через исполнитель из секции executor
+func (c *CommandsKafka) KafkaTool(tool string, args []string) error {
+	if err := kafkaTool(tool, args, os.Stdout); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Выводим список групп потребителей, groupPattern и topicPattern ограничивают группы и топики
+func (c *CommandsKafka) GroupList(client sarama.Client, groupPattern, topicPattern string) error {
+	if err := c.group.groupList(client, groupPattern, topicPattern); err != nil {
+		return err
+	}
 	return nil
 }
 
-func (c *CommandsKafka) UserCreate(filePath string) error {
-	if err := c.user.userCreate(filePath); err != nil {
+func (c *CommandsKafka) GroupDescribe(client sarama.Client, groupPattern, topicPattern string) error {
+	if err := c.group.groupDescribe(client, groupPattern, topicPattern); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *CommandsKafka) UserAddAcl(filePath string) error {
-	if err := c.user.userAddAcl(filePath); err != nil {
+// Сбрасываем смещения группы потребителей способом mode, без execute выводится только план
+func (c *CommandsKafka) GroupResetOffsets(client sarama.Client, group, topicSpec, mode, value string, execute bool) error {
+	if err := c.group.groupResetOffsets(client, group, topicSpec, mode, value, execute); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *CommandsKafka) AclList(principal string) error {
-	if err := c.acl.aclList(principal); err != nil {
+func (c *CommandsKafka) GroupDelete(client sarama.Client, groupPattern string) error {
+	if err := c.group.groupDelete(client, groupPattern); err != nil {
 		return err
 	}
 	return nil
 }
 
-// func (c *CommandsKafka) WhoTopicPart(client sarama.Client, filePath string) (map[string][]int32, error) {
+func (c *CommandsKafka) GroupDeleteOffsets(client sarama.Client, group, topicPattern string) error {
+	if err := c.group.groupDeleteOffsets(client, group, topicPattern); err != nil {
+		return err
+	}
+	return nil
+}
 
-// 	brokerIDs, err := c.broker.brokerList(client)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+// Читаем сообщения топика и выводим их в stdout
+func (c *CommandsKafka) Consume(client sarama.Client, topic string, partition int32, offset, fromTime string, limit int, follow bool, output string) error {
+	options := consumeOptions{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    offset,
+		FromTime:  fromTime,
+		Limit:     limit,
+		Follow:    follow,
+		Output:    output,
+	}
+	if err := consumeTopic(client, options, os.Stdout); err != nil {
+		return err
+	}
+	return nil
+}
 
-// 	needReplicaBrokerId, replicaBrokerId, err := c.topic.whoTopicPart(client, filePath, brokerIDs)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return needReplicaBrokerId, replicaBrokerId, nil
-// }
+// Записываем сообщения в топик из stdin или файла, партиция и смещение каждой записи выводятся в stdout
+func (c *CommandsKafka) Produce(client sarama.Client, topic, file, input, keySeparator string, partition int32, compression, acks string, idempotent bool) error {
+	options := produceOptions{
+		Topic:        topic,
+		File:         file,
+		Input:        input,
+		KeySeparator: keySeparator,
+		Partition:    partition,
+		Compression:  compression,
+		Acks:         acks,
+		Idempotent:   idempotent,
+	}
+	if err := produceTopic(client, options, os.Stdout); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+)
+
+// Кластер из одного mock брокера, который является контроллером
+func newTestCluster(t *testing.T) (*sarama.MockBroker, sarama.Client) {
+	t.Helper()
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatalf("ошибка создания клиента: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return broker, client
+}
+
+// Закрытие админ-клиента не закрывает общий клиент, и следующая команда может создать новый админ-клиент
+func TestNewClusterAdminKeepsClientOpen(t *testing.T) {
+	_, client := newTestCluster(t)
+
+	for i := 0; i < 2; i++ {
+		admin, err := newClusterAdmin(client)
+		if err != nil {
+			t.Fatalf("newClusterAdmin: %v", err)
+		}
+		if _, err := admin.Controller(); err != nil {
+			t.Fatalf("Controller: %v", err)
+		}
+		if err := admin.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if client.Closed() {
+			t.Fatal("закрытие админ-клиента закрыло общий клиент")
+		}
+	}
+}
+
+// Изменение фактора репликации создает админ-клиент в whoTopicPart, а затем снова обращается
+// к общему клиенту и создает новый админ-клиент для запуска перераспределения
+func TestTopicChangeReplicationFactorKeepsClientOpen(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+	viper.Set("reassign.dir", dir)
+
+	controller := sarama.NewMockBroker(t, 1)
+	t.Cleanup(controller.Close)
+	follower := sarama.NewMockBroker(t, 2)
+	t.Cleanup(follower.Close)
+
+	// Mock метаданные размещают реплики партиции на всех брокерах, то есть фактор репликации 2
+	handlers := map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(controller.BrokerID()).
+			SetBroker(controller.Addr(), controller.BrokerID()).
+			SetBroker(follower.Addr(), follower.BrokerID()).
+			SetLeader("orders", 0, controller.BrokerID()),
+		"DescribeConfigsRequest":             sarama.NewMockDescribeConfigsResponse(t),
+		"AlterPartitionReassignmentsRequest": sarama.NewMockAlterPartitionReassignmentsResponse(t),
+		"ListPartitionReassignmentsRequest":  sarama.NewMockListPartitionReassignmentsResponse(t),
+	}
+	controller.SetHandlerByMap(handlers)
+	follower.SetHandlerByMap(handlers)
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	client, err := sarama.NewClient([]string{controller.Addr()}, config)
+	if err != nil {
+		t.Fatalf("ошибка создания клиента: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	topicsFile := filepath.Join(dir, "topics.yaml")
+	if err := os.WriteFile(topicsFile, []byte("topics:\n  orders:\n    replicas: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewCommandKafka().TopicChangeReplicationFactor(client, topicsFile); err != nil {
+		t.Fatalf("TopicChangeReplicationFactor: %v", err)
+	}
+	if client.Closed() {
+		t.Fatal("общий клиент закрыт после изменения фактора репликации")
+	}
+
+	plan, err := loadReassignmentPlan(reassignPlanFile)
+	if err != nil {
+		t.Fatalf("план не сохранен: %v", err)
+	}
+	if len(plan.Partitions) != 1 || len(plan.Partitions[0].Replicas) != 1 || plan.Partitions[0].Replicas[0] != controller.BrokerID() {
+		t.Errorf("план %+v, ожидалась партиция orders-0 с лидером %d", plan.Partitions, controller.BrokerID())
+	}
+
+	var altered bool
+	for _, exchange := range controller.History() {
+		if _, ok := exchange.Request.(*sarama.AlterPartitionReassignmentsRequest); ok {
+			altered = true
+		}
+	}
+	if !altered {
+		t.Error("контроллер не получил запрос перераспределения")
+	}
+}
+
+// Явно указанный, но ненайденный топик не удаляется и возвращает ошибку, чтобы не выводилось сообщение об успехе
+func TestTopicDeleteMissingTopic(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	broker, client := newTestCluster(t)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()),
+		"DescribeConfigsRequest": sarama.NewMockDescribeConfigsResponse(t),
+	})
+
+	err := NewCommandKafka().TopicDelete(client, "absent", "", false)
+	if err == nil || !strings.Contains(err.Error(), "absent") {
+		t.Fatalf("ошибка %v, ожидалась ошибка с топиком absent", err)
+	}
+	for _, exchange := range broker.History() {
+		if _, ok := exchange.Request.(*sarama.DeleteTopicsRequest); ok {
+			t.Error("отправлен запрос удаления ненайденного топика")
+		}
+	}
+}
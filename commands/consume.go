@@ -0,0 +1,269 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IBM/sarama"
+)
+
+// Форматы вывода сообщений
+const (
+	outputPlain = "plain"
+	outputJSON  = "json"
+	outputHex   = "hex"
+)
+
+// Время ожидания сообщений без follow, после которого чтение завершается
+const consumeIdleTimeout = 10 * time.Second
+
+// Параметры чтения топика
+type consumeOptions struct {
+	Topic string
+	// Партиция для чтения, -1 - все партиции
+	Partition int32
+	// Начальная позиция: earliest, latest, смещение или -N (N последних сообщений партиции)
+	Offset string
+	// Начальная позиция по времени, имеет приоритет над Offset
+	FromTime string
+	// Максимальное количество сообщений, 0 - без ограничения
+	Limit  int
+	Follow bool
+	Output string
+}
+
+// Сообщение в формате JSON lines. Ключ и значение, не являющиеся UTF-8, выводятся в base64
+type consumedMessage struct {
+	Topic       string          `json:"topic"`
+	Partition   int32           `json:"partition"`
+	Offset      int64           `json:"offset"`
+	Timestamp   time.Time       `json:"timestamp"`
+	Key         *string         `json:"key"`
+	KeyBase64   string          `json:"keyBase64,omitempty"`
+	Value       *string         `json:"value"`
+	ValueBase64 string          `json:"valueBase64,omitempty"`
+	Headers     []messageHeader `json:"headers,omitempty"`
+}
+
+// Заголовок сообщения, значение, не являющееся UTF-8, выводится в base64
+type messageHeader struct {
+	Key         string  `json:"key"`
+	Value       *string `json:"value"`
+	ValueBase64 string  `json:"valueBase64,omitempty"`
+}
+
+// Текстовое поле сообщения или base64, если данные не являются UTF-8
+func textOrBase64(data []byte) (*string, string) {
+	if data == nil {
+		return nil, ""
+	}
+	if !utf8.Valid(data) {
+		return nil, base64.StdEncoding.EncodeToString(data)
+	}
+	text := string(data)
+	return &text, ""
+}
+
+// Записываем сообщение в выбранном формате
+func writeMessage(w io.Writer, output string, msg *sarama.ConsumerMessage) error {
+	switch output {
+	case outputJSON:
+		message := consumedMessage{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			Timestamp: msg.Timestamp,
+		}
+		message.Key, message.KeyBase64 = textOrBase64(msg.Key)
+		message.Value, message.ValueBase64 = textOrBase64(msg.Value)
+		for _, header := range msg.Headers {
+			entry := messageHeader{Key: string(header.Key)}
+			entry.Value, entry.ValueBase64 = textOrBase64(header.Value)
+			message.Headers = append(message.Headers, entry)
+		}
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case outputHex:
+		_, err := fmt.Fprintf(w, "%d:%d key=%s value=%s\n", msg.Partition, msg.Offset, hex.EncodeToString(msg.Key), hex.EncodeToString(msg.Value))
+		return err
+	default:
+		_, err := fmt.Fprintf(w, "%s\n", msg.Value)
+		return err
+	}
+}
+
+// Определяем начальное смещение партиции
+func consumeStartOffset(client sarama.Client, topic string, partition int32, options consumeOptions, timestamp int64) (int64, error) {
+	if options.FromTime != "" {
+		offset, err := client.GetOffset(topic, partition, timestamp)
+		if err != nil {
+			return 0, err
+		}
+		if offset < 0 {
+			// Сообщений с меткой времени не раньше заданной нет
+			return client.GetOffset(topic, partition, sarama.OffsetNewest)
+		}
+		return offset, nil
+	}
+
+	switch options.Offset {
+	case "", "earliest":
+		return client.GetOffset(topic, partition, sarama.OffsetOldest)
+	case "latest":
+		return client.GetOffset(topic, partition, sarama.OffsetNewest)
+	}
+	offset, err := strconv.ParseInt(options.Offset, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("некорректная начальная позиция %q: должна быть earliest, latest, смещение или -N", options.Offset)
+	}
+	oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, err
+	}
+	newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		offset = newest + offset
+	}
+	return min(max(offset, oldest), newest), nil
+}
+
+// Читаем сообщения топика. Без follow чтение завершается на смещении конца лога,
+// зафиксированном при запуске, с follow - по лимиту или сигналу завершения
+func consumeTopic(client sarama.Client, options consumeOptions, output io.Writer) error {
+	switch options.Output {
+	case "", outputPlain, outputJSON, outputHex:
+	default:
+		return fmt.Errorf("неподдерживаемый формат вывода %q: должен быть plain, json или hex", options.Output)
+	}
+	var timestamp int64
+	if options.FromTime != "" {
+		t, err := parseResetDatetime(options.FromTime)
+		if err != nil {
+			return err
+		}
+		timestamp = t.UnixMilli()
+	}
+
+	partitions, err := client.Partitions(options.Topic)
+	if err != nil {
+		return fmt.Errorf("ошибка получения партиций топика %s: %v", options.Topic, err)
+	}
+	if options.Partition >= 0 {
+		if !slices.Contains(partitions, options.Partition) {
+			return fmt.Errorf("партиция %d не найдена в топике %s", options.Partition, options.Topic)
+		}
+		partitions = []int32{options.Partition}
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return fmt.Errorf("ошибка создания consumer: %v", err)
+	}
+	defer consumer.Close()
+
+	messages := make(chan *sarama.ConsumerMessage)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// Смещение последнего сообщения партиции на момент запуска
+	lastOffsets := make(map[int32]int64)
+	for _, partition := range partitions {
+		start, err := consumeStartOffset(client, options.Topic, partition, options, timestamp)
+		if err != nil {
+			return fmt.Errorf("ошибка получения начального смещения %s/%d: %v", options.Topic, partition, err)
+		}
+		newest, err := client.GetOffset(options.Topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return fmt.Errorf("ошибка получения конечного смещения %s/%d: %v", options.Topic, partition, err)
+		}
+		if !options.Follow && start >= newest {
+			continue
+		}
+		lastOffsets[partition] = newest - 1
+
+		partitionConsumer, err := consumer.ConsumePartition(options.Topic, partition, start)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения партиции %s/%d: %v", options.Topic, partition, err)
+		}
+		defer partitionConsumer.AsyncClose()
+		go func() {
+			for msg := range partitionConsumer.Messages() {
+				select {
+				case messages <- msg:
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	w := bufio.NewWriter(output)
+	defer w.Flush()
+
+	// Без follow чтение также завершается, если новых сообщений нет дольше consumeIdleTimeout:
+	// последнее смещение может принадлежать служебной записи транзакции, которая не доставляется
+	idle := time.NewTimer(consumeIdleTimeout)
+	defer idle.Stop()
+
+	var count int
+	done := make(map[int32]bool)
+	for options.Follow || len(done) < len(lastOffsets) {
+		if options.Limit > 0 && count >= options.Limit {
+			break
+		}
+		select {
+		case msg := <-messages:
+			if !options.Follow && (done[msg.Partition] || msg.Offset > lastOffsets[msg.Partition]) {
+				done[msg.Partition] = true
+				continue
+			}
+			if err := writeMessage(w, options.Output, msg); err != nil {
+				return fmt.Errorf("ошибка вывода сообщения: %v", err)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			count++
+			if !options.Follow && msg.Offset >= lastOffsets[msg.Partition] {
+				done[msg.Partition] = true
+			}
+			idle.Reset(consumeIdleTimeout)
+		case <-idle.C:
+			if !options.Follow {
+				log.Printf("Нет новых сообщений дольше %s, чтение завершено", consumeIdleTimeout)
+				log.Printf("Прочитано сообщений: %d", count)
+				return nil
+			}
+			idle.Reset(consumeIdleTimeout)
+		case <-signals:
+			log.Printf("Чтение прервано")
+			log.Printf("Прочитано сообщений: %d", count)
+			return nil
+		}
+	}
+	log.Printf("Прочитано сообщений: %d", count)
+	return nil
+}
@@ -0,0 +1,282 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Исполнитель shell скриптов в окружении с CLI инструментами Kafka (kafka-*.sh).
+// Реализация выбирается параметром executor.type в config.yaml
+type kafkaExecutor interface {
+	// Выполняем скрипт через bash -c, stdin передается скрипту, возвращается stdout и stderr
+	Run(script string, stdin io.Reader) ([]byte, error)
+	String() string
+}
+
+// Экранируем аргумент для shell
+func shellQuote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// Выполняем команду локально и возвращаем объединенный вывод
+func runCommand(name string, args []string, stdin io.Reader) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return output.Bytes(), fmt.Errorf("%v: %s", err, strings.TrimSpace(output.String()))
+	}
+	return output.Bytes(), nil
+}
+
+// Выполнение в контейнере через docker exec или podman exec
+type containerExecutor struct {
+	Runtime   string
+	Container string
+}
+
+// Команда запуска скрипта в контейнере
+func (e *containerExecutor) command(script string) (string, []string) {
+	return e.Runtime, []string{"exec", "-i", e.Container, "bash", "-c", script}
+}
+
+func (e *containerExecutor) Run(script string, stdin io.Reader) ([]byte, error) {
+	name, args := e.command(script)
+	return runCommand(name, args, stdin)
+}
+
+func (e *containerExecutor) String() string {
+	return fmt.Sprintf("%s exec %s", e.Runtime, e.Container)
+}
+
+// Выполнение в поде Kubernetes через kubectl exec
+type kubectlExecutor struct {
+	Context   string
+	Namespace string
+	Pod       string
+	Container string
+}
+
+// Команда запуска скрипта в поде
+func (e *kubectlExecutor) command(script string) (string, []string) {
+	var args []string
+	if e.Context != "" {
+		args = append(args, "--context", e.Context)
+	}
+	if e.Namespace != "" {
+		args = append(args, "-n", e.Namespace)
+	}
+	args = append(args, "exec", "-i", e.Pod)
+	if e.Container != "" {
+		args = append(args, "-c", e.Container)
+	}
+	args = append(args, "--", "bash", "-c", script)
+	return "kubectl", args
+}
+
+func (e *kubectlExecutor) Run(script string, stdin io.Reader) ([]byte, error) {
+	name, args := e.command(script)
+	return runCommand(name, args, stdin)
+}
+
+func (e *kubectlExecutor) String() string {
+	return fmt.Sprintf("kubectl exec %s/%s", e.Namespace, e.Pod)
+}
+
+// Выполнение на хосте брокера по SSH
+type sshExecutor struct {
+	Host         string
+	User         string
+	Port         int
+	IdentityFile string
+}
+
+// Команда запуска скрипта на хосте по SSH
+func (e *sshExecutor) command(script string) (string, []string) {
+	args := []string{"-o", "BatchMode=yes"}
+	if e.Port > 0 {
+		args = append(args, "-p", fmt.Sprint(e.Port))
+	}
+	if e.IdentityFile != "" {
+		args = append(args, "-i", e.IdentityFile)
+	}
+	target := e.Host
+	if e.User != "" {
+		target = e.User + "@" + e.Host
+	}
+	// ssh передает удаленному shell одну строку, поэтому скрипт экранируется
+	args = append(args, target, "bash -c "+shellQuote(script))
+	return "ssh", args
+}
+
+func (e *sshExecutor) Run(script string, stdin io.Reader) ([]byte, error) {
+	name, args := e.command(script)
+	return runCommand(name, args, stdin)
+}
+
+func (e *sshExecutor) String() string {
+	return fmt.Sprintf("ssh %s", e.Host)
+}
+
+// Локальное выполнение, инструменты Kafka берутся из executor.kafkaHome
+type localExecutor struct{}
+
+func (e *localExecutor) command(script string) (string, []string) {
+	return "bash", []string{"-c", script}
+}
+
+func (e *localExecutor) Run(script string, stdin io.Reader) ([]byte, error) {
+	name, args := e.command(script)
+	return runCommand(name, args, stdin)
+}
+
+func (e *localExecutor) String() string {
+	return "local"
+}
+
+// Исполнитель без выполнения команд: запоминает скрипты и возвращает заданный вывод.
+// Используется для проверки команд без окружения Kafka (executor.type: fake)
+type fakeExecutor struct {
+	Output  []byte
+	Err     error
+	Scripts []string
+	Stdins  [][]byte
+}
+
+func (e *fakeExecutor) Run(script string, stdin io.Reader) ([]byte, error) {
+	var input []byte
+	if stdin != nil {
+		var err error
+		if input, err = io.ReadAll(stdin); err != nil {
+			return nil, err
+		}
+	}
+	e.Scripts = append(e.Scripts, script)
+	e.Stdins = append(e.Stdins, input)
+	log.Printf("[fake] %s", script)
+	return e.Output, e.Err
+}
+
+func (e *fakeExecutor) String() string {
+	return "fake"
+}
+
+// Создаем исполнитель по секции executor файла конфигурации.
+// Для docker используется executor.container, для совместимости со старым конфигом - container.name
+func newKafkaExecutor() (kafkaExecutor, error) {
+	containerName := viper.GetString("executor.container")
+	if containerName == "" {
+		containerName = viper.GetString("container.name")
+	}
+	if containerName == "" {
+		containerName = "kafka"
+	}
+
+	switch executorType := viper.GetString("executor.type"); executorType {
+	case "", "docker", "podman":
+		runtime := executorType
+		if runtime == "" {
+			runtime = "docker"
+		}
+		return &containerExecutor{Runtime: runtime, Container: containerName}, nil
+	case "kubectl":
+		pod := viper.GetString("executor.kubectl.pod")
+		if pod == "" {
+			return nil, fmt.Errorf("для kubectl необходимо указать executor.kubectl.pod")
+		}
+		return &kubectlExecutor{
+			Context:   viper.GetString("executor.kubectl.context"),
+			Namespace: viper.GetString("executor.kubectl.namespace"),
+			Pod:       pod,
+			Container: viper.GetString("executor.kubectl.container"),
+		}, nil
+	case "ssh":
+		host := viper.GetString("executor.ssh.host")
+		if host == "" {
+			return nil, fmt.Errorf("для ssh необходимо указать executor.ssh.host")
+		}
+		return &sshExecutor{
+			Host:         host,
+			User:         viper.GetString("executor.ssh.user"),
+			Port:         viper.GetInt("executor.ssh.port"),
+			IdentityFile: viper.GetString("executor.ssh.identityFile"),
+		}, nil
+	case "local":
+		if viper.GetString("executor.kafkaHome") == "" {
+			return nil, fmt.Errorf("для local необходимо указать executor.kafkaHome")
+		}
+		return &localExecutor{}, nil
+	case "fake":
+		return &fakeExecutor{}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый executor.type %q: должен быть docker, podman, kubectl, ssh, local или fake", executorType)
+	}
+}
+
+// Путь к инструменту Kafka: executor.kafkaHome/bin/<tool> или имя инструмента из PATH
+func kafkaToolPath(tool string) string {
+	if kafkaHome := viper.GetString("executor.kafkaHome"); kafkaHome != "" {
+		return path.Join(kafkaHome, "bin", tool)
+	}
+	return tool
+}
+
+// Запускаем CLI инструмент Kafka через исполнитель с параметрами клиента текущего профиля.
+// Параметры клиента передаются через stdin во временный файл, доступный только владельцу,
+// и удаляются после выполнения
+func runKafkaTool(executor kafkaExecutor, tool string, args []string) ([]byte, error) {
+	if strings.ContainsAny(tool, "/ ") {
+		return nil, fmt.Errorf("некорректное имя инструмента %q", tool)
+	}
+	props, err := clientProperties()
+	if err != nil {
+		return nil, err
+	}
+	var config bytes.Buffer
+	if err := writeClientProperties(&config, props); err != nil {
+		return nil, err
+	}
+
+	bootstrap := viper.GetString("executor.bootstrapServer")
+	if bootstrap == "" {
+		bootstrap = strings.Join(viper.GetStringSlice("kafka.broker"), ",")
+	}
+	command := []string{shellQuote(kafkaToolPath(tool)), "--bootstrap-server", shellQuote(bootstrap), "--command-config", `"$CONFIG"`}
+	for _, arg := range args {
+		command = append(command, shellQuote(arg))
+	}
+	script := fmt.Sprintf(`umask 077 && CONFIG=$(mktemp) && cat > "$CONFIG" || exit 1
+%s
+RC=$?
+rm -f "$CONFIG"
+exit $RC`, strings.Join(command, " "))
+
+	log.Printf("Выполнение %s через %s", tool, executor)
+	return executor.Run(script, &config)
+}
+
+// Выполняем инструмент Kafka через исполнитель из конфигурации и выводим результат
+func kafkaTool(tool string, args []string, output io.Writer) error {
+	executor, err := newKafkaExecutor()
+	if err != nil {
+		return err
+	}
+	result, err := runKafkaTool(executor, tool, args)
+	if err != nil {
+		return fmt.Errorf("ошибка выполнения %s: %v", tool, err)
+	}
+	_, err = output.Write(result)
+	return err
+}
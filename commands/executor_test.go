@@ -0,0 +1,253 @@
+package commands
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Команда запуска скрипта исполнителем без выполнения
+type commandExecutor interface {
+	kafkaExecutor
+	command(script string) (string, []string)
+}
+
+func TestNewKafkaExecutorCommand(t *testing.T) {
+	const script = "echo 'ok'"
+	tests := []struct {
+		name     string
+		config   map[string]any
+		wantName string
+		wantArgs []string
+	}{
+		{
+			name:     "docker по умолчанию",
+			config:   map[string]any{},
+			wantName: "docker",
+			wantArgs: []string{"exec", "-i", "kafka", "bash", "-c", script},
+		},
+		{
+			name:     "docker с container.name",
+			config:   map[string]any{"executor.type": "docker", "container.name": "broker-1"},
+			wantName: "docker",
+			wantArgs: []string{"exec", "-i", "broker-1", "bash", "-c", script},
+		},
+		{
+			name:     "podman",
+			config:   map[string]any{"executor.type": "podman", "executor.container": "kafka-2"},
+			wantName: "podman",
+			wantArgs: []string{"exec", "-i", "kafka-2", "bash", "-c", script},
+		},
+		{
+			name: "kubectl",
+			config: map[string]any{
+				"executor.type":              "kubectl",
+				"executor.kubectl.context":   "prod",
+				"executor.kubectl.namespace": "kafka",
+				"executor.kubectl.pod":       "kafka-0",
+				"executor.kubectl.container": "broker",
+			},
+			wantName: "kubectl",
+			wantArgs: []string{"--context", "prod", "-n", "kafka", "exec", "-i", "kafka-0", "-c", "broker", "--", "bash", "-c", script},
+		},
+		{
+			name:     "kubectl без контекста и контейнера",
+			config:   map[string]any{"executor.type": "kubectl", "executor.kubectl.pod": "kafka-0"},
+			wantName: "kubectl",
+			wantArgs: []string{"exec", "-i", "kafka-0", "--", "bash", "-c", script},
+		},
+		{
+			name: "ssh",
+			config: map[string]any{
+				"executor.type":             "ssh",
+				"executor.ssh.host":         "kafka-1.example.com",
+				"executor.ssh.user":         "kafka",
+				"executor.ssh.port":         2222,
+				"executor.ssh.identityFile": "/home/kafka/.ssh/id_ed25519",
+			},
+			wantName: "ssh",
+			wantArgs: []string{"-o", "BatchMode=yes", "-p", "2222", "-i", "/home/kafka/.ssh/id_ed25519",
+				"kafka@kafka-1.example.com", `bash -c 'echo '\''ok'\'''`},
+		},
+		{
+			name:     "local",
+			config:   map[string]any{"executor.type": "local", "executor.kafkaHome": "/opt/kafka"},
+			wantName: "bash",
+			wantArgs: []string{"-c", script},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for key, value := range tt.config {
+				viper.Set(key, value)
+			}
+
+			executor, err := newKafkaExecutor()
+			if err != nil {
+				t.Fatalf("newKafkaExecutor: %v", err)
+			}
+			commander, ok := executor.(commandExecutor)
+			if !ok {
+				t.Fatalf("исполнитель %s не формирует команду", executor)
+			}
+			name, args := commander.command(script)
+			if name != tt.wantName || !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("command() = %s %q, ожидалось %s %q", name, args, tt.wantName, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestNewKafkaExecutorErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"kubectl без pod", map[string]any{"executor.type": "kubectl"}},
+		{"ssh без host", map[string]any{"executor.type": "ssh"}},
+		{"local без kafkaHome", map[string]any{"executor.type": "local"}},
+		{"неизвестный тип", map[string]any{"executor.type": "nomad"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for key, value := range tt.config {
+				viper.Set(key, value)
+			}
+			if _, err := newKafkaExecutor(); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "''"},
+		{"--list", "'--list'"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME;`id`", "'$HOME;`id`'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.value); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, ожидалось %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// Аргументы доходят до инструмента без изменений: скрипт выполняется локальным bash,
+// а для ssh - так же, как его выполнит удаленный shell
+func TestExecutorQuotingRoundTrip(t *testing.T) {
+	args := []string{"--describe", "it's", "a b", "$HOME", "`id`", `"quoted"`, ""}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	script := `printf '[%s]\n' ` + strings.Join(quoted, " ")
+	want := ""
+	for _, arg := range args {
+		want += "[" + arg + "]\n"
+	}
+
+	output, err := (&localExecutor{}).Run(script, nil)
+	if err != nil {
+		t.Fatalf("local: %v", err)
+	}
+	if string(output) != want {
+		t.Errorf("local: %q, ожидалось %q", output, want)
+	}
+
+	_, sshArgs := (&sshExecutor{Host: "kafka-1"}).command(script)
+	remote := sshArgs[len(sshArgs)-1]
+	output, err = runCommand("bash", []string{"-c", remote}, nil)
+	if err != nil {
+		t.Fatalf("ssh: %v", err)
+	}
+	if string(output) != want {
+		t.Errorf("ssh: %q, ожидалось %q", output, want)
+	}
+}
+
+func TestRunKafkaToolWithFakeExecutor(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     map[string]any
+		tool       string
+		args       []string
+		wantScript string
+	}{
+		{
+			name:       "инструмент из PATH",
+			config:     map[string]any{"kafka.broker": []string{"k1:9092", "k2:9092"}},
+			tool:       "kafka-topics.sh",
+			args:       []string{"--list"},
+			wantScript: `'kafka-topics.sh' --bootstrap-server 'k1:9092,k2:9092' --command-config "$CONFIG" '--list'`,
+		},
+		{
+			name: "kafkaHome и bootstrapServer",
+			config: map[string]any{
+				"kafka.broker":             []string{"k1:9092"},
+				"executor.kafkaHome":       "/opt/kafka",
+				"executor.bootstrapServer": "localhost:9092",
+			},
+			tool:       "kafka-configs.sh",
+			args:       []string{"--entity-name", "it's", "--add-config", "retention.ms=1000"},
+			wantScript: `'/opt/kafka/bin/kafka-configs.sh' --bootstrap-server 'localhost:9092' --command-config "$CONFIG" '--entity-name' 'it'\''s' '--add-config' 'retention.ms=1000'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for key, value := range tt.config {
+				viper.Set(key, value)
+			}
+
+			executor := &fakeExecutor{Output: []byte("ok\n")}
+			output, err := runKafkaTool(executor, tt.tool, tt.args)
+			if err != nil {
+				t.Fatalf("runKafkaTool: %v", err)
+			}
+			if string(output) != "ok\n" {
+				t.Errorf("вывод %q, ожидалось %q", output, "ok\n")
+			}
+			if len(executor.Scripts) != 1 {
+				t.Fatalf("выполнено скриптов: %d, ожидался 1", len(executor.Scripts))
+			}
+			lines := strings.Split(executor.Scripts[0], "\n")
+			if !strings.HasPrefix(lines[0], "umask 077") {
+				t.Errorf("скрипт не начинается с umask 077: %q", lines[0])
+			}
+			if lines[1] != tt.wantScript {
+				t.Errorf("команда:\n%s\nожидалось:\n%s", lines[1], tt.wantScript)
+			}
+			if !strings.Contains(executor.Scripts[0], `rm -f "$CONFIG"`) {
+				t.Error("временный файл параметров не удаляется")
+			}
+			if !strings.Contains(string(executor.Stdins[0]), "security.protocol=PLAINTEXT\n") {
+				t.Errorf("параметры клиента не переданы через stdin: %q", executor.Stdins[0])
+			}
+		})
+	}
+}
+
+func TestRunKafkaToolRejectsToolPath(t *testing.T) {
+	for _, tool := range []string{"../bin/kafka-topics.sh", "kafka-topics.sh --list"} {
+		executor := &fakeExecutor{}
+		if _, err := runKafkaTool(executor, tool, nil); err == nil {
+			t.Errorf("ожидалась ошибка для %q", tool)
+		}
+		if len(executor.Scripts) != 0 {
+			t.Errorf("скрипт выполнен для %q", tool)
+		}
+	}
+}
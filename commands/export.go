@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/IBM/sarama"
+	"gopkg.in/yaml.v3"
+)
+
+// Записываем YAML файл в директорию экспорта
+func writeExportFile(dir, fileName string, value interface{}) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("ошибка создания директории %s: %v", dir, err)
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("ошибка формирования YAML: %v", err)
+	}
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %v", path, err)
+	}
+	log.Printf("Файл %s сохранен", path)
+	return nil
+}
+
+// Выгружаем топики кластера в формате, который принимает --createTopic:
+// партиции, фактор репликации и только параметры, заданные на уровне топика
+func (c *Topic) topicExport(client sarama.Client, dir string, topicFilter *regexp.Regexp) error {
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	// Получаем список топиков из kafka
+	topics, err := admin.ListTopics()
+	if err != nil {
+		log.Printf("Ошибка получения списка топиков: %v", err)
+		return err
+	}
+
+	topicsConfig := make(map[string]map[string]interface{})
+	for topicName, detail := range topics {
+		if strings.HasPrefix(topicName, "__") {
+			continue
+		}
+		if topicFilter != nil && !topicFilter.MatchString(topicName) {
+			continue
+		}
+
+		params := map[string]interface{}{
+			"partitions": detail.NumPartitions,
+			"replicas":   detail.ReplicationFactor,
+		}
+		config, err := describeTopicConfig(admin, topicName)
+		if err != nil {
+			return fmt.Errorf("ошибка получения конфигурации топика %s: %v", topicName, err)
+		}
+		for key, entry := range config {
+			if entry.Source == sarama.SourceTopic && !entry.Sensitive {
+				params[key] = entry.Value
+			}
+		}
+		topicsConfig[topicName] = params
+	}
+
+	log.Printf("Выгружено топиков: %d", len(topicsConfig))
+	return writeExportFile(dir, "topics.yaml", map[string]interface{}{"topics": topicsConfig})
+}
+
+// Преобразуем имя операции sarama в формат YAML: DescribeConfigs -> describe-configs
+func aclOperationName(operation sarama.AclOperation) string {
+	var b strings.Builder
+	for i, r := range operation.String() {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteRune('-')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Преобразуем ACL кластера в формат YAML
+func aclConfigFromSarama(resource sarama.Resource, acl sarama.Acl) aclConfig {
+	cfg := aclConfig{
+		Allow:               acl.PermissionType == sarama.AclPermissionAllow,
+		Operation:           aclOperationName(acl.Operation),
+		ResourcePatternType: strings.ToLower(resource.ResourcePatternType.String()),
+	}
+	if acl.Host != "*" {
+		cfg.Host = acl.Host
+	}
+	switch resource.ResourceType {
+	case sarama.AclResourceTopic:
+		cfg.Topic = resource.ResourceName
+	case sarama.AclResourceGroup:
+		cfg.Group = resource.ResourceName
+	case sarama.AclResourceCluster:
+		cfg.Cluster = resource.ResourceName
+	case sarama.AclResourceTransactionalID:
+		cfg.TransactionalID = resource.ResourceName
+	case sarama.AclResourceDelegationToken:
+		cfg.DelegationToken = resource.ResourceName
+	}
+	return cfg
+}
+
+// Выгружаем пользователей SCRAM и ACL принципалов в формате, который принимает --createUserAcl.
+// Пароли в кластере не хранятся и не выгружаются
+func (u *User) userExport(client sarama.Client, dir string, principal string) error {
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	users := make(map[string]*userConfig)
+	getUser := func(name string) *userConfig {
+		if users[name] == nil {
+			users[name] = &userConfig{Username: name}
+		}
+		return users[name]
+	}
+
+	// ACL принципалов
+	resourceAcls, err := listAcls(admin, principal)
+	if err != nil {
+		return fmt.Errorf("ошибка получения списка ACL: %v", err)
+	}
+	for _, resourceAcl := range resourceAcls {
+		for _, acl := range resourceAcl.Acls {
+			name := strings.TrimPrefix(acl.Principal, "User:")
+			user := getUser(name)
+			user.Acls = append(user.Acls, aclConfigFromSarama(resourceAcl.Resource, *acl))
+		}
+	}
+
+	// Механизмы SCRAM пользователей
+	var describeUsers []string
+	if principal != "" && principal != "*" {
+		describeUsers = []string{strings.TrimPrefix(userPrincipal(principal), "User:")}
+	}
+	results, err := admin.DescribeUserScramCredentials(describeUsers)
+	if err != nil {
+		log.Printf("Не удалось получить пользователей SCRAM: %v", err)
+	}
+	for _, result := range results {
+		if result.ErrorCode != sarama.ErrNoError || len(result.CredentialInfos) == 0 {
+			continue
+		}
+		user := getUser(result.User)
+		for _, info := range result.CredentialInfos {
+			user.Mechanisms = append(user.Mechanisms, info.Mechanism.String())
+			user.Iterations = max(user.Iterations, info.Iterations)
+		}
+		slices.Sort(user.Mechanisms)
+	}
+
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	exported := make([]userConfig, 0, len(names))
+	for _, name := range names {
+		user := users[name]
+		slices.SortFunc(user.Acls, func(a, b aclConfig) int {
+			return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		})
+		exported = append(exported, *user)
+	}
+
+	log.Printf("Выгружено пользователей: %d", len(exported))
+	return writeExportFile(dir, "users.yaml", map[string]interface{}{"users": exported})
+}
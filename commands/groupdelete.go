@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// Удаляем группы потребителей без активных участников, группы с участниками пропускаются
+func deleteEmptyGroups(admin sarama.ClusterAdmin, descriptions []*sarama.GroupDescription) (deleted, active, failed []string) {
+	for _, description := range descriptions {
+		if len(description.Members) > 0 {
+			log.Printf("Группа %s не удалена: активных участников %d (%s)", description.GroupId, len(description.Members), description.State)
+			active = append(active, description.GroupId)
+			continue
+		}
+		if err := admin.DeleteConsumerGroup(description.GroupId); err != nil {
+			log.Printf("Ошибка удаления группы %s: %v", description.GroupId, err)
+			failed = append(failed, description.GroupId)
+			continue
+		}
+		log.Printf("Группа %s успешно удалена", description.GroupId)
+		deleted = append(deleted, description.GroupId)
+	}
+	return deleted, active, failed
+}
+
+// Топики, по которым у группы есть зафиксированные смещения
+func groupCommittedTopics(admin sarama.ClusterAdmin, group string) ([]string, error) {
+	response, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения смещений группы %s: %v", group, err)
+	}
+	var topics []string
+	for topic, blocks := range response.Blocks {
+		for _, block := range blocks {
+			if block.Err == sarama.ErrNoError && block.Offset >= 0 {
+				topics = append(topics, topic)
+				break
+			}
+		}
+	}
+	slices.Sort(topics)
+	return topics, nil
+}
+
+// Находим группы, которые читали только указанные топики
+func groupsConsumingOnly(admin sarama.ClusterAdmin, topics []string) ([]*sarama.GroupDescription, error) {
+	descriptions, err := describeGroups(admin, "")
+	if err != nil {
+		return nil, err
+	}
+	var result []*sarama.GroupDescription
+	for _, description := range descriptions {
+		committed, err := groupCommittedTopics(admin, description.GroupId)
+		if err != nil {
+			return nil, err
+		}
+		if len(committed) == 0 {
+			continue
+		}
+		only := true
+		for _, topic := range committed {
+			if !slices.Contains(topics, topic) {
+				only = false
+				break
+			}
+		}
+		if only {
+			result = append(result, description)
+		}
+	}
+	return result, nil
+}
+
+// Удаляем группы потребителей без активных участников, имена которых подходят под шаблон
+func (g *Group) groupDelete(client sarama.Client, groupPattern string) error {
+	if groupPattern == "" {
+		return fmt.Errorf("необходимо указать группу или шаблон в --group")
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	descriptions, err := describeGroups(admin, groupPattern)
+	if err != nil {
+		return err
+	}
+	if len(descriptions) == 0 {
+		return fmt.Errorf("не найдено групп потребителей по %q", groupPattern)
+	}
+
+	deleted, active, failed := deleteEmptyGroups(admin, descriptions)
+
+	// Итоговый отчет
+	log.Printf("Удалено: %d %v", len(deleted), deleted)
+	log.Printf("С активными участниками: %d %v", len(active), active)
+	log.Printf("Ошибки удаления: %d %v", len(failed), failed)
+
+	if len(failed) > 0 || len(active) > 0 {
+		return fmt.Errorf("не удалось удалить группы: %s", strings.Join(append(active, failed...), ", "))
+	}
+	return nil
+}
+
+// Удаляем зафиксированные смещения группы по топикам, подходящим под шаблон.
+// Kafka не удаляет смещения топика, на который группа подписана в данный момент
+func (g *Group) groupDeleteOffsets(client sarama.Client, group, topicPattern string) error {
+	if group == "" || strings.ContainsAny(group, "*?[") {
+		return fmt.Errorf("для удаления смещений необходимо указать имя группы в --group")
+	}
+	if topicPattern == "" {
+		return fmt.Errorf("для удаления смещений необходимо указать топик в --topic")
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	response, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка получения смещений группы %s: %v", group, err)
+	}
+
+	var deleted int
+	var failed []string
+	for topic, blocks := range response.Blocks {
+		if !matchPattern(topicPattern, topic) {
+			continue
+		}
+		partitions := make([]int32, 0, len(blocks))
+		for partition, block := range blocks {
+			if block.Err == sarama.ErrNoError && block.Offset >= 0 {
+				partitions = append(partitions, partition)
+			}
+		}
+		slices.Sort(partitions)
+		var topicDeleted []int32
+		for _, partition := range partitions {
+			if err := admin.DeleteConsumerGroupOffset(group, topic, partition); err != nil {
+				log.Printf("Ошибка удаления смещения группы %s %s/%d: %v", group, topic, partition, err)
+				failed = append(failed, fmt.Sprintf("%s/%d", topic, partition))
+				continue
+			}
+			topicDeleted = append(topicDeleted, partition)
+		}
+		if len(topicDeleted) > 0 {
+			deleted += len(topicDeleted)
+			log.Printf("Смещения группы %s по топику %s удалены (партиции: %s)", group, topic, joinPartitions(topicDeleted))
+		}
+	}
+
+	if deleted == 0 && len(failed) == 0 {
+		return fmt.Errorf("у группы %s нет зафиксированных смещений по %q", group, topicPattern)
+	}
+	log.Printf("Удалено смещений: %d", deleted)
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return fmt.Errorf("не удалось удалить смещения: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Способы сброса смещений группы, совпадают с kafka-consumer-groups.sh --reset-offsets
+const (
+	resetToEarliest = "to-earliest"
+	resetToLatest   = "to-latest"
+	resetToOffset   = "to-offset"
+	resetShiftBy    = "shift-by"
+	resetToDatetime = "to-datetime"
+	resetByDuration = "by-duration"
+	resetFromFile   = "from-file"
+)
+
+// Форматы даты для to-datetime, без часового пояса используется локальное время
+var resetDatetimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Новое смещение группы по партиции
+type offsetReset struct {
+	Topic     string
+	Partition int32
+	Current   int64
+	Earliest  int64
+	Latest    int64
+	New       int64
+}
+
+// Разбираем дату для to-datetime
+func parseResetDatetime(value string) (time.Time, error) {
+	for _, layout := range resetDatetimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("некорректная дата %q, ожидается формат 2006-01-02T15:04:05[Z07:00]", value)
+}
+
+// Разбираем топики для сброса: "topic" или "topic:0,1,2", имя топика может быть шаблоном
+func resetTopicPartitions(client sarama.Client, topicSpec string) (map[string][]int32, error) {
+	topicPattern, partitionList, hasPartitions := strings.Cut(topicSpec, ":")
+	var explicit []int32
+	if hasPartitions {
+		for _, value := range strings.Split(partitionList, ",") {
+			partition, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("некорректный номер партиции %q", value)
+			}
+			explicit = append(explicit, int32(partition))
+		}
+	}
+
+	topics, err := client.Topics()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка топиков: %v", err)
+	}
+	result := make(map[string][]int32)
+	for _, topic := range topics {
+		if !matchPattern(topicPattern, topic) {
+			continue
+		}
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения партиций топика %s: %v", topic, err)
+		}
+		if !hasPartitions {
+			result[topic] = partitions
+			continue
+		}
+		for _, partition := range explicit {
+			if !slices.Contains(partitions, partition) {
+				return nil, fmt.Errorf("партиция %d не найдена в топике %s", partition, topic)
+			}
+		}
+		result[topic] = explicit
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("не найдено топиков по %q", topicPattern)
+	}
+	return result, nil
+}
+
+// Читаем смещения из CSV файла в формате kafka-consumer-groups.sh: topic,partition,offset
+func readResetFile(filePath string) (map[string]map[int32]int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла %s: %v", filePath, err)
+	}
+	defer file.Close()
+
+	offsets := make(map[string]map[int32]int64)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: ожидается topic,partition,offset", filePath, lineNumber)
+		}
+		partition, err := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: некорректный номер партиции %q", filePath, lineNumber, fields[1])
+		}
+		offset, err := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: некорректное смещение %q", filePath, lineNumber, fields[2])
+		}
+		topic := strings.TrimSpace(fields[0])
+		if offsets[topic] == nil {
+			offsets[topic] = make(map[int32]int64)
+		}
+		offsets[topic][int32(partition)] = offset
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла %s: %v", filePath, err)
+	}
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("не найдено смещений в файле %s", filePath)
+	}
+	return offsets, nil
+}
+
+// Вычисляем новые смещения группы по выбранному способу сброса.
+// Новое смещение ограничивается диапазоном доступных сообщений партиции
+func planOffsetReset(admin sarama.ClusterAdmin, client sarama.Client, group, topicSpec, mode, value string) ([]offsetReset, error) {
+	var fileOffsets map[string]map[int32]int64
+	var offsetValue, timestamp int64
+	var err error
+	switch mode {
+	case resetToEarliest, resetToLatest:
+	case resetToOffset, resetShiftBy:
+		if offsetValue, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("для %s необходимо указать число в --resetValue", mode)
+		}
+	case resetToDatetime:
+		t, err := parseResetDatetime(value)
+		if err != nil {
+			return nil, err
+		}
+		timestamp = t.UnixMilli()
+	case resetByDuration:
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("для %s необходимо указать длительность в --resetValue, например 1h30m", mode)
+		}
+		timestamp = time.Now().Add(-duration).UnixMilli()
+	case resetFromFile:
+		if fileOffsets, err = readResetFile(value); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("неподдерживаемый способ сброса %q: должен быть %s", mode, strings.Join([]string{
+			resetToEarliest, resetToLatest, resetToOffset, resetShiftBy, resetToDatetime, resetByDuration, resetFromFile}, ", "))
+	}
+
+	// Партиции для сброса: из файла, из --topic или все партиции с зафиксированными смещениями группы
+	committed, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения смещений группы %s: %v", group, err)
+	}
+	topicPartitions := make(map[string][]int32)
+	switch {
+	case fileOffsets != nil:
+		for topic, partitions := range fileOffsets {
+			for partition := range partitions {
+				topicPartitions[topic] = append(topicPartitions[topic], partition)
+			}
+		}
+	case topicSpec != "":
+		if topicPartitions, err = resetTopicPartitions(client, topicSpec); err != nil {
+			return nil, err
+		}
+	default:
+		for topic, blocks := range committed.Blocks {
+			for partition, block := range blocks {
+				if block.Err == sarama.ErrNoError && block.Offset >= 0 {
+					topicPartitions[topic] = append(topicPartitions[topic], partition)
+				}
+			}
+		}
+		if len(topicPartitions) == 0 {
+			return nil, fmt.Errorf("у группы %s нет зафиксированных смещений, укажите --topic", group)
+		}
+	}
+
+	var resets []offsetReset
+	for topic, partitions := range topicPartitions {
+		for _, partition := range partitions {
+			reset := offsetReset{Topic: topic, Partition: partition, Current: -1}
+			if block := committed.GetBlock(topic, partition); block != nil && block.Err == sarama.ErrNoError {
+				reset.Current = block.Offset
+			}
+			if reset.Earliest, err = client.GetOffset(topic, partition, sarama.OffsetOldest); err != nil {
+				return nil, fmt.Errorf("ошибка получения начального смещения %s/%d: %v", topic, partition, err)
+			}
+			if reset.Latest, err = client.GetOffset(topic, partition, sarama.OffsetNewest); err != nil {
+				return nil, fmt.Errorf("ошибка получения конечного смещения %s/%d: %v", topic, partition, err)
+			}
+
+			switch mode {
+			case resetToEarliest:
+				reset.New = reset.Earliest
+			case resetToLatest:
+				reset.New = reset.Latest
+			case resetToOffset:
+				reset.New = offsetValue
+			case resetShiftBy:
+				if reset.Current < 0 {
+					return nil, fmt.Errorf("у группы %s нет зафиксированного смещения %s/%d для %s", group, topic, partition, mode)
+				}
+				reset.New = reset.Current + offsetValue
+			case resetToDatetime, resetByDuration:
+				// Смещение первого сообщения с меткой времени не раньше заданной, если таких нет - конец лога
+				if reset.New, err = client.GetOffset(topic, partition, timestamp); err != nil {
+					return nil, fmt.Errorf("ошибка получения смещения по времени %s/%d: %v", topic, partition, err)
+				}
+				if reset.New < 0 {
+					reset.New = reset.Latest
+				}
+			case resetFromFile:
+				reset.New = fileOffsets[topic][partition]
+			}
+			reset.New = min(max(reset.New, reset.Earliest), reset.Latest)
+			resets = append(resets, reset)
+		}
+	}
+	slices.SortFunc(resets, func(a, b offsetReset) int {
+		if c := strings.Compare(a.Topic, b.Topic); c != 0 {
+			return c
+		}
+		return int(a.Partition - b.Partition)
+	})
+	return resets, nil
+}
+
+// Выводим старые и новые смещения группы по партициям
+func printOffsetReset(group string, resets []offsetReset) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tTOPIC\tPARTITION\tCURRENT-OFFSET\tNEW-OFFSET\tEARLIEST\tLATEST")
+	for _, reset := range resets {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%d\t%d\n", group, reset.Topic, reset.Partition,
+			formatOffset(reset.Current), reset.New, reset.Earliest, reset.Latest)
+	}
+	return w.Flush()
+}
+
+// Фиксируем новые смещения группы через offset manager и проверяем результат
+func commitOffsetReset(admin sarama.ClusterAdmin, client sarama.Client, group string, resets []offsetReset) error {
+	offsetManager, err := sarama.NewOffsetManagerFromClient(group, client)
+	if err != nil {
+		return fmt.Errorf("ошибка создания offset manager: %v", err)
+	}
+
+	var partitionManagers []sarama.PartitionOffsetManager
+	for _, reset := range resets {
+		partitionManager, err := offsetManager.ManagePartition(reset.Topic, reset.Partition)
+		if err != nil {
+			offsetManager.Close()
+			return fmt.Errorf("ошибка управления смещением %s/%d: %v", reset.Topic, reset.Partition, err)
+		}
+		partitionManagers = append(partitionManagers, partitionManager)
+		// ResetOffset только уменьшает смещение, MarkOffset только увеличивает
+		if next, _ := partitionManager.NextOffset(); reset.New < next {
+			partitionManager.ResetOffset(reset.New, "")
+		} else {
+			partitionManager.MarkOffset(reset.New, "")
+		}
+	}
+	offsetManager.Commit()
+	for _, partitionManager := range partitionManagers {
+		partitionManager.AsyncClose()
+	}
+	if err := offsetManager.Close(); err != nil {
+		return fmt.Errorf("ошибка закрытия offset manager: %v", err)
+	}
+
+	// Ошибки фиксации offset manager не возвращает, поэтому смещения перечитываются
+	committed, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка проверки смещений группы %s: %v", group, err)
+	}
+	var failed []string
+	for _, reset := range resets {
+		block := committed.GetBlock(reset.Topic, reset.Partition)
+		if block == nil || block.Err != sarama.ErrNoError || block.Offset != reset.New {
+			failed = append(failed, fmt.Sprintf("%s/%d", reset.Topic, reset.Partition))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("смещения не зафиксированы для партиций: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Сбрасываем смещения группы потребителей. Без execute выводится только план изменений.
+// Сброс выполняется только для существующей группы без активных участников
+func (g *Group) groupResetOffsets(client sarama.Client, group, topicSpec, mode, value string, execute bool) error {
+	if group == "" || strings.ContainsAny(group, "*?[") {
+		return fmt.Errorf("для сброса смещений необходимо указать имя группы в --group")
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	// Фиксация смещений для несуществующей группы молча создала бы ее
+	groups, err := admin.ListConsumerGroups()
+	if err != nil {
+		return fmt.Errorf("ошибка получения списка групп потребителей: %v", err)
+	}
+	if _, ok := groups[group]; !ok {
+		return fmt.Errorf("группа потребителей %s не найдена", group)
+	}
+
+	descriptions, err := admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return fmt.Errorf("ошибка получения описания группы %s: %v", group, err)
+	}
+	for _, description := range descriptions {
+		if description.Err != sarama.ErrNoError {
+			return fmt.Errorf("ошибка получения описания группы %s: %v", group, description.Err)
+		}
+		if len(description.Members) > 0 {
+			return fmt.Errorf("у группы %s есть активные участники (%d, состояние %s), остановите потребителей перед сбросом смещений",
+				group, len(description.Members), description.State)
+		}
+	}
+
+	resets, err := planOffsetReset(admin, client, group, topicSpec, mode, value)
+	if err != nil {
+		return err
+	}
+	if err := printOffsetReset(group, resets); err != nil {
+		return err
+	}
+	if !execute {
+		log.Printf("⚠️ Смещения не изменены, для применения запустите с --execute")
+		return nil
+	}
+
+	if err := commitOffsetReset(admin, client, group, resets); err != nil {
+		return err
+	}
+	log.Printf("Смещения группы %s изменены для партиций: %d", group, len(resets))
+	return nil
+}
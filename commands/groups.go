@@ -0,0 +1,324 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/IBM/sarama"
+)
+
+type Group struct{}
+
+// Смещение группы потребителей по партиции топика
+type groupPartitionOffset struct {
+	Topic     string
+	Partition int32
+	// Зафиксированное смещение группы, -1 если смещение не зафиксировано
+	Committed int64
+	LogEnd    int64
+	// Участник группы, которому назначена партиция
+	MemberID string
+	ClientID string
+	Host     string
+}
+
+// Отставание группы по партиции, -1 если смещение не зафиксировано
+func (o groupPartitionOffset) lag() int64 {
+	if o.Committed < 0 || o.LogEnd < 0 {
+		return -1
+	}
+	return max(o.LogEnd-o.Committed, 0)
+}
+
+// Совпадение имени с шаблоном фильтра, пустой шаблон совпадает с любым именем
+func matchPattern(pattern, name string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// Получаем описание групп потребителей, имена которых подходят под шаблон
+func describeGroups(admin sarama.ClusterAdmin, groupPattern string) ([]*sarama.GroupDescription, error) {
+	groups, err := admin.ListConsumerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка групп потребителей: %v", err)
+	}
+	var names []string
+	for name := range groups {
+		if matchPattern(groupPattern, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	slices.Sort(names)
+
+	descriptions, err := admin.DescribeConsumerGroups(names)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения описания групп потребителей: %v", err)
+	}
+	for _, description := range descriptions {
+		if description.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("ошибка получения описания группы %s: %v", description.GroupId, description.Err)
+		}
+	}
+	slices.SortFunc(descriptions, func(a, b *sarama.GroupDescription) int {
+		return strings.Compare(a.GroupId, b.GroupId)
+	})
+	return descriptions, nil
+}
+
+// Назначение партиций участникам группы: топик -> партиция -> участник
+func groupAssignments(description *sarama.GroupDescription) map[string]map[int32]*sarama.GroupMemberDescription {
+	assignments := make(map[string]map[int32]*sarama.GroupMemberDescription)
+	for _, member := range description.Members {
+		// Назначение разбирается только для групп потребителей (protocol type consumer)
+		assignment, err := member.GetMemberAssignment()
+		if err != nil || assignment == nil {
+			continue
+		}
+		for topic, partitions := range assignment.Topics {
+			if assignments[topic] == nil {
+				assignments[topic] = make(map[int32]*sarama.GroupMemberDescription)
+			}
+			for _, partition := range partitions {
+				assignments[topic][partition] = member
+			}
+		}
+	}
+	return assignments
+}
+
+// Собираем зафиксированные смещения, смещения конца лога и назначение партиций группы
+// для топиков, подходящих под шаблон
+func groupOffsets(admin sarama.ClusterAdmin, client sarama.Client, description *sarama.GroupDescription, topicPattern string) ([]groupPartitionOffset, error) {
+	response, err := admin.ListConsumerGroupOffsets(description.GroupId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения смещений группы %s: %v", description.GroupId, err)
+	}
+
+	offsets := make(map[string]map[int32]*groupPartitionOffset)
+	getOffset := func(topic string, partition int32) *groupPartitionOffset {
+		if offsets[topic] == nil {
+			offsets[topic] = make(map[int32]*groupPartitionOffset)
+		}
+		if offsets[topic][partition] == nil {
+			offsets[topic][partition] = &groupPartitionOffset{Topic: topic, Partition: partition, Committed: -1, LogEnd: -1}
+		}
+		return offsets[topic][partition]
+	}
+
+	for topic, blocks := range response.Blocks {
+		if !matchPattern(topicPattern, topic) {
+			continue
+		}
+		for partition, block := range blocks {
+			if block.Err != sarama.ErrNoError || block.Offset < 0 {
+				continue
+			}
+			getOffset(topic, partition).Committed = block.Offset
+		}
+	}
+	for topic, partitions := range groupAssignments(description) {
+		if !matchPattern(topicPattern, topic) {
+			continue
+		}
+		for partition, member := range partitions {
+			offset := getOffset(topic, partition)
+			offset.MemberID = member.MemberId
+			offset.ClientID = member.ClientId
+			offset.Host = member.ClientHost
+		}
+	}
+
+	var result []groupPartitionOffset
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			logEnd, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				log.Printf("Не удалось получить смещение конца лога %s/%d: %v", topic, partition, err)
+			} else {
+				offset.LogEnd = logEnd
+			}
+			result = append(result, *offset)
+		}
+	}
+	slices.SortFunc(result, func(a, b groupPartitionOffset) int {
+		if c := strings.Compare(a.Topic, b.Topic); c != 0 {
+			return c
+		}
+		return int(a.Partition - b.Partition)
+	})
+	return result, nil
+}
+
+// Суммарное отставание группы и признак того, что отставание известно хотя бы по одной партиции
+func totalLag(offsets []groupPartitionOffset) (int64, bool) {
+	var total int64
+	var known bool
+	for _, offset := range offsets {
+		if lag := offset.lag(); lag >= 0 {
+			total += lag
+			known = true
+		}
+	}
+	return total, known
+}
+
+func formatOffset(offset int64) string {
+	if offset < 0 {
+		return "-"
+	}
+	return strconv.FormatInt(offset, 10)
+}
+
+// Форматируем список партиций через запятую в порядке возрастания
+func joinPartitions(partitions []int32) string {
+	partitions = slices.Clone(partitions)
+	slices.Sort(partitions)
+	parts := make([]string, len(partitions))
+	for i, partition := range partitions {
+		parts[i] = strconv.Itoa(int(partition))
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatOptional(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// Выводим список групп потребителей: состояние, количество участников, топики и суммарное отставание
+func (g *Group) groupList(client sarama.Client, groupPattern, topicPattern string) error {
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	descriptions, err := describeGroups(admin, groupPattern)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tSTATE\tPROTOCOL TYPE\tMEMBERS\tTOPICS\tLAG")
+	var count int
+	for _, description := range descriptions {
+		offsets, err := groupOffsets(admin, client, description, topicPattern)
+		if err != nil {
+			return err
+		}
+		if topicPattern != "" && len(offsets) == 0 {
+			continue
+		}
+		var topics []string
+		for _, offset := range offsets {
+			topics = append(topics, offset.Topic)
+		}
+		topics = slices.Compact(topics)
+
+		lag := "-"
+		if total, known := totalLag(offsets); known {
+			lag = strconv.FormatInt(total, 10)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", description.GroupId, description.State,
+			formatOptional(description.ProtocolType), len(description.Members), formatOptional(strings.Join(topics, ",")), lag)
+		count++
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Найдено групп потребителей: %d", count)
+	return nil
+}
+
+// Выводим подробное описание групп потребителей: участники и смещения по партициям
+func (g *Group) groupDescribe(client sarama.Client, groupPattern, topicPattern string) error {
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	descriptions, err := describeGroups(admin, groupPattern)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	for _, description := range descriptions {
+		offsets, err := groupOffsets(admin, client, description, topicPattern)
+		if err != nil {
+			return err
+		}
+		if topicPattern != "" && len(offsets) == 0 {
+			continue
+		}
+		count++
+
+		fmt.Printf("\nGROUP %s (%s, участников: %d)\n\n", description.GroupId, description.State, len(description.Members))
+
+		// Участники группы с назначенными партициями
+		if len(description.Members) > 0 {
+			memberIDs := make([]string, 0, len(description.Members))
+			for memberID := range description.Members {
+				memberIDs = append(memberIDs, memberID)
+			}
+			slices.Sort(memberIDs)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "MEMBER ID\tCLIENT ID\tHOST\tASSIGNMENT")
+			for _, memberID := range memberIDs {
+				member := description.Members[memberID]
+				var assigned []string
+				if assignment, err := member.GetMemberAssignment(); err == nil && assignment != nil {
+					for topic, partitions := range assignment.Topics {
+						if !matchPattern(topicPattern, topic) {
+							continue
+						}
+						assigned = append(assigned, topic+":"+joinPartitions(partitions))
+					}
+				}
+				slices.Sort(assigned)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", member.MemberId, member.ClientId, member.ClientHost, formatOptional(strings.Join(assigned, " ")))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+
+		// Смещения и отставание по партициям
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TOPIC\tPARTITION\tCURRENT-OFFSET\tLOG-END-OFFSET\tLAG\tCONSUMER-ID\tHOST\tCLIENT-ID")
+		for _, offset := range offsets {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", offset.Topic, offset.Partition,
+				formatOffset(offset.Committed), formatOffset(offset.LogEnd), formatOffset(offset.lag()),
+				formatOptional(offset.MemberID), formatOptional(offset.Host), formatOptional(offset.ClientID))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if total, known := totalLag(offsets); known {
+			fmt.Printf("Суммарное отставание: %d\n", total)
+		}
+	}
+	log.Printf("Найдено групп потребителей: %d", count)
+	return nil
+}
@@ -0,0 +1,456 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Планировщик перераспределения с минимальным перемещением реплик.
+// В отличие от --generate в Kafka, который заново раскладывает все реплики топика,
+// планировщик переносит реплики только с удаляемых брокеров и на новые брокеры,
+// пока нагрузка в рамках топика не выровняется, а затем балансирует лидеров
+// перестановкой реплик внутри партиции (без перемещения данных).
+// При наличии стоек (broker.rack) реплики партиции размещаются в как можно большем числе стоек
+type reassignmentPlanner struct {
+	brokers []int32
+	// Стойки брокеров, пустая строка - стойка не задана
+	racks map[int32]string
+	// Количество реплик на брокерах по всему кластеру, используется при равной нагрузке в топике
+	clusterLoad map[int32]int
+}
+
+func newReassignmentPlanner(current *reassignmentPlan, brokers []int32, racks map[int32]string) *reassignmentPlanner {
+	p := &reassignmentPlanner{
+		brokers:     slices.Clone(brokers),
+		racks:       racks,
+		clusterLoad: make(map[int32]int),
+	}
+	slices.Sort(p.brokers)
+	for _, broker := range p.brokers {
+		p.clusterLoad[broker] = 0
+	}
+	for _, partition := range current.Partitions {
+		for _, replica := range partition.Replicas {
+			if _, ok := p.clusterLoad[replica]; ok {
+				p.clusterLoad[replica]++
+			}
+		}
+	}
+	return p
+}
+
+// Строим план с минимальным перемещением реплик для целевого набора брокеров
+func planMinimalMovement(current *reassignmentPlan, brokers []int32, racks map[int32]string) (*reassignmentPlan, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("список брокеров пуст")
+	}
+	p := newReassignmentPlanner(current, brokers, racks)
+
+	byTopic := current.byTopic()
+	topics := make([]string, 0, len(byTopic))
+	for topic := range byTopic {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	proposed := &reassignmentPlan{Version: 1}
+	for _, topic := range topics {
+		partitions, err := p.planTopic(topic, byTopic[topic])
+		if err != nil {
+			return nil, err
+		}
+		proposed.Partitions = append(proposed.Partitions, partitions...)
+	}
+	return proposed, nil
+}
+
+func (p *reassignmentPlanner) isTarget(broker int32) bool {
+	_, ok := p.clusterLoad[broker]
+	return ok
+}
+
+// Количество различных стоек среди целевых брокеров
+func (p *reassignmentPlanner) rackCount() int {
+	racks := make(map[string]struct{})
+	for _, broker := range p.brokers {
+		racks[p.racks[broker]] = struct{}{}
+	}
+	return len(racks)
+}
+
+func (p *reassignmentPlanner) planTopic(topic string, current []partitionReassignment) ([]partitionReassignment, error) {
+	partitions := make([]partitionReassignment, len(current))
+	topicLoad := make(map[int32]int, len(p.brokers))
+	for _, broker := range p.brokers {
+		topicLoad[broker] = 0
+	}
+	for i, partition := range current {
+		if len(partition.Replicas) > len(p.brokers) {
+			return nil, fmt.Errorf("топик %s: фактор репликации %d больше количества брокеров %d", topic, len(partition.Replicas), len(p.brokers))
+		}
+		partitions[i] = partitionReassignment{
+			Topic:     partition.Topic,
+			Partition: partition.Partition,
+			Replicas:  slices.Clone(partition.Replicas),
+		}
+		for _, replica := range partition.Replicas {
+			if p.isTarget(replica) {
+				topicLoad[replica]++
+			}
+		}
+	}
+
+	move := func(partition *partitionReassignment, index int, to int32) {
+		from := partition.Replicas[index]
+		if p.isTarget(from) {
+			topicLoad[from]--
+			p.clusterLoad[from]--
+		}
+		partition.Replicas[index] = to
+		topicLoad[to]++
+		p.clusterLoad[to]++
+	}
+
+	// Переносим реплики с брокеров, которых нет в целевом наборе, на наименее нагруженные.
+	// Позиция реплики сохраняется, поэтому замена лидера становится новым предпочтительным лидером
+	for i := range partitions {
+		for index, replica := range partitions[i].Replicas {
+			if p.isTarget(replica) {
+				continue
+			}
+			target, ok := p.leastLoaded(topicLoad, partitions[i].Replicas, index)
+			if !ok {
+				return nil, fmt.Errorf("топик %s: нет свободного брокера для реплики партиции %d", topic, partitions[i].Partition)
+			}
+			move(&partitions[i], index, target)
+		}
+	}
+
+	// Разносим реплики партиций по стойкам: если несколько реплик находятся в одной стойке,
+	// а в кластере есть неиспользованные партицией стойки, одна из них переносится туда
+	maxRacks := p.rackCount()
+	for i := range partitions {
+		want := min(len(partitions[i].Replicas), maxRacks)
+		for rackDiversity(partitions[i].Replicas, p.racks) < want {
+			index := duplicateRackReplica(partitions[i].Replicas, p.racks)
+			target, ok := p.leastLoaded(topicLoad, partitions[i].Replicas, index)
+			if !ok || rackDiversity(replaceReplica(partitions[i].Replicas, index, target), p.racks) <= rackDiversity(partitions[i].Replicas, p.racks) {
+				break
+			}
+			move(&partitions[i], index, target)
+		}
+	}
+
+	// Выравниваем количество реплик топика на брокерах, перенося реплики с самых
+	// нагруженных брокеров на самые свободные, пока разница больше одной реплики
+	for limit := len(partitions) * len(p.brokers); limit > 0; limit-- {
+		moved := false
+		for _, from := range p.sortedByLoad(topicLoad, true) {
+			for _, to := range p.sortedByLoad(topicLoad, false) {
+				if topicLoad[from]-topicLoad[to] <= 1 {
+					break
+				}
+				if i, index, ok := p.findReplicaToMove(partitions, from, to); ok {
+					move(&partitions[i], index, to)
+					moved = true
+					break
+				}
+			}
+			if moved {
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+
+	balanceLeaders(partitions, p.brokers)
+	return partitions, nil
+}
+
+// Строим план изменения фактора репликации. Для каждого топика используются брокеры,
+// на которых уже есть его реплики, и свободные брокеры. Новые реплики добавляются в конец
+// списка на наименее нагруженные брокеры с предпочтением новых стоек, при уменьшении
+// удаляются реплики-последователи так, чтобы сохранить как можно больше стоек.
+// Лидеры партиций не меняются
+func planReplicationFactor(current *reassignmentPlan, factors map[string]int, topicBrokers map[string][]int32, racks map[int32]string) (*reassignmentPlan, error) {
+	var brokers []int32
+	for _, ids := range topicBrokers {
+		for _, id := range ids {
+			if !slices.Contains(brokers, id) {
+				brokers = append(brokers, id)
+			}
+		}
+	}
+	p := newReassignmentPlanner(current, brokers, racks)
+
+	byTopic := current.byTopic()
+	topics := make([]string, 0, len(factors))
+	for topic := range factors {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	proposed := &reassignmentPlan{Version: 1}
+	for _, topic := range topics {
+		factor := factors[topic]
+		if factor > len(topicBrokers[topic]) {
+			return nil, fmt.Errorf("топик %s: фактор репликации %d больше количества доступных брокеров %d", topic, factor, len(topicBrokers[topic]))
+		}
+
+		topicLoad := make(map[int32]int)
+		for _, broker := range p.brokers {
+			topicLoad[broker] = 0
+		}
+		for _, partition := range byTopic[topic] {
+			for _, replica := range partition.Replicas {
+				topicLoad[replica]++
+			}
+		}
+
+		for _, partition := range byTopic[topic] {
+			replicas := slices.Clone(partition.Replicas)
+			for len(replicas) < factor {
+				target, ok := p.leastLoadedFrom(topicLoad, replicas, -1, topicBrokers[topic])
+				if !ok {
+					return nil, fmt.Errorf("топик %s: нет свободного брокера для реплики партиции %d", topic, partition.Partition)
+				}
+				replicas = append(replicas, target)
+				topicLoad[target]++
+				p.clusterLoad[target]++
+			}
+			for len(replicas) > factor {
+				index := p.replicaToRemove(topicLoad, replicas)
+				topicLoad[replicas[index]]--
+				p.clusterLoad[replicas[index]]--
+				replicas = slices.Delete(replicas, index, index+1)
+			}
+			proposed.Partitions = append(proposed.Partitions, partitionReassignment{
+				Topic:     topic,
+				Partition: partition.Partition,
+				Replicas:  replicas,
+			})
+		}
+	}
+	return proposed, nil
+}
+
+// Выбираем реплику-последователя для удаления: в первую очередь ту, без которой
+// количество стоек партиции не уменьшится, затем с наиболее нагруженного брокера
+func (p *reassignmentPlanner) replicaToRemove(topicLoad map[int32]int, replicas []int32) int {
+	best := -1
+	bestKeepsRacks := false
+	diversity := rackDiversity(replicas, p.racks)
+	for i := 1; i < len(replicas); i++ {
+		keepsRacks := rackDiversity(slices.Delete(slices.Clone(replicas), i, i+1), p.racks) == diversity
+		switch {
+		case best < 0,
+			keepsRacks && !bestKeepsRacks,
+			keepsRacks == bestKeepsRacks && p.loadGreater(topicLoad, replicas[i], replicas[best]):
+			best, bestKeepsRacks = i, keepsRacks
+		}
+	}
+	return best
+}
+
+// Сравниваем нагрузку брокеров: в топике, затем в кластере
+func (p *reassignmentPlanner) loadGreater(topicLoad map[int32]int, a, b int32) bool {
+	if topicLoad[a] != topicLoad[b] {
+		return topicLoad[a] > topicLoad[b]
+	}
+	return p.clusterLoad[a] > p.clusterLoad[b]
+}
+
+// Выбираем брокер для замены реплики replicas[index]: среди брокеров, которых еще нет
+// в партиции, предпочитаем стойки, не занятые остальными репликами, затем наименьшую нагрузку
+func (p *reassignmentPlanner) leastLoaded(topicLoad map[int32]int, replicas []int32, index int) (int32, bool) {
+	return p.leastLoadedFrom(topicLoad, replicas, index, p.brokers)
+}
+
+// То же, что leastLoaded, но с выбором только из указанных брокеров
+func (p *reassignmentPlanner) leastLoadedFrom(topicLoad map[int32]int, replicas []int32, index int, candidates []int32) (int32, bool) {
+	used := make(map[string]struct{})
+	for i, replica := range replicas {
+		if i != index {
+			used[p.racks[replica]] = struct{}{}
+		}
+	}
+	fallback, found := int32(0), false
+	for _, broker := range p.sortedByLoad(topicLoad, false) {
+		if slices.Contains(replicas, broker) || !slices.Contains(candidates, broker) {
+			continue
+		}
+		if _, ok := used[p.racks[broker]]; !ok {
+			return broker, true
+		}
+		if !found {
+			fallback, found = broker, true
+		}
+	}
+	return fallback, found
+}
+
+// Сортируем брокеры по нагрузке в топике, затем по нагрузке в кластере и по id
+func (p *reassignmentPlanner) sortedByLoad(topicLoad map[int32]int, desc bool) []int32 {
+	brokers := slices.Clone(p.brokers)
+	slices.SortStableFunc(brokers, func(a, b int32) int {
+		if topicLoad[a] != topicLoad[b] {
+			return topicLoad[a] - topicLoad[b]
+		}
+		if p.clusterLoad[a] != p.clusterLoad[b] {
+			return p.clusterLoad[a] - p.clusterLoad[b]
+		}
+		return int(a - b)
+	})
+	if desc {
+		slices.Reverse(brokers)
+	}
+	return brokers
+}
+
+// Ищем реплику на брокере from в партиции, где еще нет брокера to и перенос
+// не уменьшает количество стоек партиции.
+// Предпочтение отдается репликам-последователям, чтобы не менять лидера
+func (p *reassignmentPlanner) findReplicaToMove(partitions []partitionReassignment, from, to int32) (int, int, bool) {
+	partitionIndex, replicaIndex := -1, -1
+	for i, partition := range partitions {
+		if slices.Contains(partition.Replicas, to) {
+			continue
+		}
+		index := slices.Index(partition.Replicas, from)
+		if index < 0 {
+			continue
+		}
+		if rackDiversity(replaceReplica(partition.Replicas, index, to), p.racks) < rackDiversity(partition.Replicas, p.racks) {
+			continue
+		}
+		if index > 0 {
+			return i, index, true
+		}
+		if partitionIndex < 0 {
+			partitionIndex, replicaIndex = i, index
+		}
+	}
+	return partitionIndex, replicaIndex, partitionIndex >= 0
+}
+
+// Балансируем предпочтительных лидеров (первая реплика) между брокерами,
+// переставляя реплики внутри партиции - данные при этом не перемещаются
+func balanceLeaders(partitions []partitionReassignment, brokers []int32) {
+	leaders := make(map[int32]int, len(brokers))
+	for _, broker := range brokers {
+		leaders[broker] = 0
+	}
+	for _, partition := range partitions {
+		leaders[partition.Replicas[0]]++
+	}
+
+	for limit := len(partitions) * len(brokers); limit > 0; limit-- {
+		sorted := slices.Clone(brokers)
+		slices.SortStableFunc(sorted, func(a, b int32) int {
+			return leaders[a] - leaders[b]
+		})
+		swapped := false
+		for i := len(sorted) - 1; i >= 0 && !swapped; i-- {
+			from := sorted[i]
+			for _, to := range sorted {
+				if leaders[from]-leaders[to] <= 1 {
+					break
+				}
+				for j := range partitions {
+					replicas := partitions[j].Replicas
+					if replicas[0] != from {
+						continue
+					}
+					if index := slices.Index(replicas, to); index > 0 {
+						replicas[0], replicas[index] = replicas[index], replicas[0]
+						leaders[from]--
+						leaders[to]++
+						swapped = true
+						break
+					}
+				}
+				if swapped {
+					break
+				}
+			}
+		}
+		if !swapped {
+			return
+		}
+	}
+}
+
+// Считаем количество перемещаемых реплик: реплики, которые появляются на новых брокерах.
+// Изменение порядка реплик внутри партиции перемещением не считается
+func countReplicaMoves(current, proposed *reassignmentPlan) int {
+	currentReplicas := make(map[string]map[int32][]int32)
+	for _, partition := range current.Partitions {
+		if currentReplicas[partition.Topic] == nil {
+			currentReplicas[partition.Topic] = make(map[int32][]int32)
+		}
+		currentReplicas[partition.Topic][partition.Partition] = partition.Replicas
+	}
+	moves := 0
+	for _, partition := range proposed.Partitions {
+		existing := currentReplicas[partition.Topic][partition.Partition]
+		for _, replica := range partition.Replicas {
+			if !slices.Contains(existing, replica) {
+				moves++
+			}
+		}
+	}
+	return moves
+}
+
+// Количество различных стоек среди реплик партиции
+func rackDiversity(replicas []int32, racks map[int32]string) int {
+	seen := make(map[string]struct{}, len(replicas))
+	for _, replica := range replicas {
+		seen[racks[replica]] = struct{}{}
+	}
+	return len(seen)
+}
+
+// Находим реплику, стойка которой уже занята другой репликой партиции.
+// Лидер (первая реплика) переносится только если других кандидатов нет
+func duplicateRackReplica(replicas []int32, racks map[int32]string) int {
+	for i := len(replicas) - 1; i >= 0; i-- {
+		for j := 0; j < i; j++ {
+			if racks[replicas[i]] == racks[replicas[j]] {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+func replaceReplica(replicas []int32, index int, broker int32) []int32 {
+	result := slices.Clone(replicas)
+	result[index] = broker
+	return result
+}
+
+// Проверяем, что план не уменьшает количество стоек ни для одной партиции
+// по сравнению с текущим распределением, и возвращаем список таких партиций
+func rackDiversityReductions(current, proposed *reassignmentPlan, racks map[int32]string) []string {
+	currentReplicas := make(map[string][]int32)
+	for _, partition := range current.Partitions {
+		currentReplicas[fmt.Sprintf("%s-%d", partition.Topic, partition.Partition)] = partition.Replicas
+	}
+	var reduced []string
+	for _, partition := range proposed.Partitions {
+		key := fmt.Sprintf("%s-%d", partition.Topic, partition.Partition)
+		existing, ok := currentReplicas[key]
+		if !ok {
+			continue
+		}
+		// При уменьшении фактора репликации стоек не может быть больше, чем реплик
+		before, after := rackDiversity(existing, racks), rackDiversity(partition.Replicas, racks)
+		if after < min(before, len(partition.Replicas)) {
+			reduced = append(reduced, fmt.Sprintf("%s (стоек %d -> %d)", key, before, after))
+		}
+	}
+	return reduced
+}
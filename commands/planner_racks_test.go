@@ -0,0 +1,66 @@
+package commands
+
+import "testing"
+
+// Размещение реплик с учетом стоек брокеров
+func TestPlanMinimalMovementRacks(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *reassignmentPlan
+		brokers []int32
+		racks   map[int32]string
+		// Минимальное количество стоек у каждой партиции плана
+		wantRacks int
+		wantMoves int
+	}{
+		{
+			// Реплики в двух стойках, новые брокеры в третьей: по одной реплике каждой партиции переносится в стойку c
+			name:      "новая стойка",
+			current:   testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 1}, []int32{3, 1, 2}, []int32{1, 3, 2}),
+			brokers:   []int32{1, 2, 3, 4, 5},
+			racks:     map[int32]string{1: "a", 2: "a", 3: "b", 4: "c", 5: "c"},
+			wantRacks: 3,
+			wantMoves: 4,
+		},
+		{
+			// Реплики удаляемого брокера стойки a переносятся на брокер той же стойки, хотя брокер
+			// стойки b так же свободен, затем одна реплика стойки b выравнивает нагрузку на брокере 5
+			name:      "удаление брокера со стойкой",
+			current:   testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 1}, []int32{3, 1, 2}),
+			brokers:   []int32{2, 3, 4, 5},
+			racks:     map[int32]string{1: "a", 2: "b", 3: "c", 4: "a", 5: "b"},
+			wantRacks: 3,
+			wantMoves: 4,
+		},
+		{
+			name:      "стоек меньше, чем реплик",
+			current:   testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 4}, []int32{3, 4, 1}, []int32{4, 1, 2}),
+			brokers:   []int32{1, 2, 3, 4},
+			racks:     map[int32]string{1: "a", 2: "b", 3: "a", 4: "b"},
+			wantRacks: 2,
+			wantMoves: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposed, err := planMinimalMovement(tt.current, tt.brokers, tt.racks)
+			if err != nil {
+				t.Fatalf("planMinimalMovement: %v", err)
+			}
+			checkProposedPlan(t, tt.current, proposed, tt.brokers, len(tt.current.Partitions[0].Replicas))
+
+			for _, partition := range proposed.Partitions {
+				if racks := rackDiversity(partition.Replicas, tt.racks); racks < tt.wantRacks {
+					t.Errorf("%s-%d: реплики %v в %d стойках, ожидалось не меньше %d",
+						partition.Topic, partition.Partition, partition.Replicas, racks, tt.wantRacks)
+				}
+			}
+			if reduced := rackDiversityReductions(tt.current, proposed, tt.racks); len(reduced) > 0 {
+				t.Errorf("план уменьшает количество стоек: %v", reduced)
+			}
+			if moves := countReplicaMoves(tt.current, proposed); moves != tt.wantMoves {
+				t.Errorf("перемещений реплик %d, ожидалось %d", moves, tt.wantMoves)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package commands
+
+import "testing"
+
+// Изменение фактора репликации с сохранением лидеров и стоек
+func TestPlanReplicationFactor(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   *reassignmentPlan
+		factor    int
+		brokers   []int32
+		racks     map[int32]string
+		wantRacks int
+		wantMoves int
+	}{
+		{
+			// Новые реплики размещаются в стойке c, которой еще нет у партиций
+			name:      "увеличение с новой стойкой",
+			current:   testPlan("orders", []int32{1, 2}, []int32{2, 1}, []int32{1, 2}),
+			factor:    3,
+			brokers:   []int32{1, 2, 3, 4},
+			racks:     map[int32]string{1: "a", 2: "b", 3: "a", 4: "c"},
+			wantRacks: 3,
+			wantMoves: 3,
+		},
+		{
+			// Удаляется реплика-последователь из повторяющейся стойки
+			name:      "уменьшение с сохранением стоек",
+			current:   testPlan("orders", []int32{1, 2, 3}, []int32{3, 1, 2}, []int32{2, 3, 1}),
+			factor:    2,
+			brokers:   []int32{1, 2, 3},
+			racks:     map[int32]string{1: "a", 2: "a", 3: "b"},
+			wantRacks: 2,
+			wantMoves: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposed, err := planReplicationFactor(tt.current, map[string]int{"orders": tt.factor},
+				map[string][]int32{"orders": tt.brokers}, tt.racks)
+			if err != nil {
+				t.Fatalf("planReplicationFactor: %v", err)
+			}
+			checkProposedPlan(t, tt.current, proposed, tt.brokers, tt.factor)
+
+			for i, partition := range proposed.Partitions {
+				if leader := tt.current.Partitions[i].Replicas[0]; partition.Replicas[0] != leader {
+					t.Errorf("%s-%d: лидер изменился с %d на %d", partition.Topic, partition.Partition, leader, partition.Replicas[0])
+				}
+				if racks := rackDiversity(partition.Replicas, tt.racks); racks < tt.wantRacks {
+					t.Errorf("%s-%d: реплики %v в %d стойках, ожидалось %d",
+						partition.Topic, partition.Partition, partition.Replicas, racks, tt.wantRacks)
+				}
+			}
+			if moves := countReplicaMoves(tt.current, proposed); moves != tt.wantMoves {
+				t.Errorf("перемещений реплик %d, ожидалось %d", moves, tt.wantMoves)
+			}
+		})
+	}
+
+	if _, err := planReplicationFactor(testPlan("orders", []int32{1, 2}), map[string]int{"orders": 3},
+		map[string][]int32{"orders": {1, 2}}, nil); err == nil {
+		t.Error("ожидалась ошибка для фактора репликации больше количества брокеров")
+	}
+}
@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+)
+
+// План одного топика с репликами партиций 0..N
+func testPlan(topic string, assignment ...[]int32) *reassignmentPlan {
+	plan := &reassignmentPlan{Version: 1}
+	for i, replicas := range assignment {
+		plan.Partitions = append(plan.Partitions, partitionReassignment{
+			Topic:     topic,
+			Partition: int32(i),
+			Replicas:  slices.Clone(replicas),
+		})
+	}
+	return plan
+}
+
+// Количество реплик и лидеров на брокерах
+func testLoad(plan *reassignmentPlan, brokers []int32) (replicas, leaders map[int32]int) {
+	replicas = make(map[int32]int, len(brokers))
+	leaders = make(map[int32]int, len(brokers))
+	for _, broker := range brokers {
+		replicas[broker], leaders[broker] = 0, 0
+	}
+	for _, partition := range plan.Partitions {
+		leaders[partition.Replicas[0]]++
+		for _, replica := range partition.Replicas {
+			replicas[replica]++
+		}
+	}
+	return replicas, leaders
+}
+
+func testSpread(load map[int32]int) int {
+	lowest, highest := -1, 0
+	for _, count := range load {
+		if lowest < 0 || count < lowest {
+			lowest = count
+		}
+		highest = max(highest, count)
+	}
+	return highest - lowest
+}
+
+// Проверяем, что план сохраняет партиции и фактор репликации и использует только целевых брокеров
+func checkProposedPlan(t *testing.T, current, proposed *reassignmentPlan, brokers []int32, factor int) {
+	t.Helper()
+	if len(proposed.Partitions) != len(current.Partitions) {
+		t.Fatalf("партиций в плане %d, ожидалось %d", len(proposed.Partitions), len(current.Partitions))
+	}
+	for _, partition := range proposed.Partitions {
+		if len(partition.Replicas) != factor {
+			t.Errorf("%s-%d: реплик %d, ожидалось %d", partition.Topic, partition.Partition, len(partition.Replicas), factor)
+		}
+		seen := make(map[int32]bool)
+		for _, replica := range partition.Replicas {
+			if !slices.Contains(brokers, replica) {
+				t.Errorf("%s-%d: реплика на брокере %d вне целевого набора %v", partition.Topic, partition.Partition, replica, brokers)
+			}
+			if seen[replica] {
+				t.Errorf("%s-%d: повторяющаяся реплика %d в %v", partition.Topic, partition.Partition, replica, partition.Replicas)
+			}
+			seen[replica] = true
+		}
+	}
+}
+
+func TestPlanMinimalMovement(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   *reassignmentPlan
+		brokers   []int32
+		factor    int
+		wantMoves int
+	}{
+		{
+			name:      "без изменения набора брокеров",
+			current:   testPlan("orders", []int32{1, 2}, []int32{2, 3}, []int32{3, 1}),
+			brokers:   []int32{1, 2, 3},
+			factor:    2,
+			wantMoves: 0,
+		},
+		{
+			// 12 реплик на 4 брокерах: на новый брокер переносятся 3 реплики
+			name:      "добавление брокера",
+			current:   testPlan("orders", []int32{1, 2}, []int32{2, 3}, []int32{3, 1}, []int32{1, 2}, []int32{2, 3}, []int32{3, 1}),
+			brokers:   []int32{1, 2, 3, 4},
+			factor:    2,
+			wantMoves: 3,
+		},
+		{
+			// 18 реплик на 6 брокерах: на каждый из двух новых брокеров переносятся 3 реплики
+			name: "добавление двух брокеров",
+			current: testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 4}, []int32{3, 4, 1}, []int32{4, 1, 2},
+				[]int32{1, 2, 3}, []int32{2, 3, 4}),
+			brokers:   []int32{1, 2, 3, 4, 5, 6},
+			factor:    3,
+			wantMoves: 6,
+		},
+		{
+			// Переносятся только 4 реплики удаляемого брокера
+			name: "удаление брокера",
+			current: testPlan("orders", []int32{1, 2}, []int32{2, 3}, []int32{3, 4}, []int32{4, 1},
+				[]int32{1, 3}, []int32{2, 4}, []int32{3, 1}, []int32{4, 2}),
+			brokers:   []int32{1, 2, 3},
+			factor:    2,
+			wantMoves: 4,
+		},
+		{
+			name:      "замена брокера",
+			current:   testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 1}, []int32{3, 1, 2}),
+			brokers:   []int32{1, 2, 4},
+			factor:    3,
+			wantMoves: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposed, err := planMinimalMovement(tt.current, tt.brokers, nil)
+			if err != nil {
+				t.Fatalf("planMinimalMovement: %v", err)
+			}
+			checkProposedPlan(t, tt.current, proposed, tt.brokers, tt.factor)
+
+			if moves := countReplicaMoves(tt.current, proposed); moves != tt.wantMoves {
+				t.Errorf("перемещений реплик %d, ожидалось %d", moves, tt.wantMoves)
+			}
+			replicas, leaders := testLoad(proposed, tt.brokers)
+			if spread := testSpread(replicas); spread > 1 {
+				t.Errorf("неравномерное распределение реплик: %v", replicas)
+			}
+			if spread := testSpread(leaders); spread > 1 {
+				t.Errorf("неравномерное распределение лидеров: %v", leaders)
+			}
+		})
+	}
+}
+
+func TestPlanMinimalMovementErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *reassignmentPlan
+		brokers []int32
+	}{
+		{"пустой список брокеров", testPlan("orders", []int32{1, 2}), nil},
+		{"фактор репликации больше количества брокеров", testPlan("orders", []int32{1, 2, 3}), []int32{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := planMinimalMovement(tt.current, tt.brokers, nil); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+func TestBalanceLeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *reassignmentPlan
+		brokers []int32
+	}{
+		{
+			name:    "все лидеры на одном брокере",
+			current: testPlan("orders", []int32{1, 2}, []int32{1, 2}, []int32{1, 3}, []int32{1, 3}, []int32{1, 2, 3}, []int32{1, 3, 2}),
+			brokers: []int32{1, 2, 3},
+		},
+		{
+			name:    "лидеры уже сбалансированы",
+			current: testPlan("orders", []int32{1, 2}, []int32{2, 3}, []int32{3, 1}),
+			brokers: []int32{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposed := &reassignmentPlan{Version: 1}
+			for _, partition := range tt.current.Partitions {
+				partition.Replicas = slices.Clone(partition.Replicas)
+				proposed.Partitions = append(proposed.Partitions, partition)
+			}
+			balanceLeaders(proposed.Partitions, tt.brokers)
+
+			// Лидеры меняются только перестановкой реплик внутри партиции
+			if moves := countReplicaMoves(tt.current, proposed); moves != 0 {
+				t.Errorf("перемещений реплик %d, ожидалось 0", moves)
+			}
+			_, leaders := testLoad(proposed, tt.brokers)
+			if spread := testSpread(leaders); spread > 1 {
+				t.Errorf("неравномерное распределение лидеров: %v", leaders)
+			}
+		})
+	}
+}
+
+func TestCountReplicaMoves(t *testing.T) {
+	current := testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 4})
+	tests := []struct {
+		name     string
+		proposed *reassignmentPlan
+		want     int
+	}{
+		{"без изменений", testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 4}), 0},
+		{"перестановка реплик", testPlan("orders", []int32{3, 1, 2}, []int32{4, 2, 3}), 0},
+		{"замена реплики", testPlan("orders", []int32{1, 2, 5}, []int32{2, 3, 4}), 1},
+		{"замена всех реплик партиции", testPlan("orders", []int32{4, 5, 6}, []int32{2, 3, 4}), 3},
+		{"увеличение фактора репликации", testPlan("orders", []int32{1, 2, 3, 4}, []int32{2, 3, 4, 1}), 2},
+		{"уменьшение фактора репликации", testPlan("orders", []int32{1, 2}, []int32{2, 3}), 0},
+		{"партиция вне текущего распределения", testPlan("orders", []int32{1, 2, 3}, []int32{2, 3, 4}, []int32{1, 2, 3}), 3},
+	}
+	for _, tt := range tests {
+		if got := countReplicaMoves(current, tt.proposed); got != tt.want {
+			t.Errorf("%s: перемещений %d, ожидалось %d", tt.name, got, tt.want)
+		}
+	}
+}
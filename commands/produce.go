@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+)
+
+// Форматы входных данных
+const (
+	inputLines = "lines"
+	inputJSON  = "json"
+)
+
+// Максимальный размер строки входных данных
+const maxProduceLineSize = 16 << 20
+
+// Параметры записи сообщений в топик
+type produceOptions struct {
+	Topic string
+	// Файл с сообщениями, "" или "-" - stdin
+	File string
+	// Формат входных данных: lines (строка - сообщение) или json (JSON lines)
+	Input string
+	// Разделитель ключа и значения в строке для формата lines, пустой - без ключа
+	KeySeparator string
+	// Партиция для всех сообщений, -1 - по ключу
+	Partition   int32
+	Compression string
+	Acks        string
+	Idempotent  bool
+}
+
+// Сообщение в формате JSON lines, совместимо с выводом --consume --output json
+type producedMessage struct {
+	Key         *string         `json:"key"`
+	KeyBase64   string          `json:"keyBase64"`
+	Value       *string         `json:"value"`
+	ValueBase64 string          `json:"valueBase64"`
+	Headers     []messageHeader `json:"headers"`
+	Partition   *int32          `json:"partition"`
+}
+
+// Явно заданная партиция сообщения, передается через ProducerMessage.Metadata
+type explicitPartition int32
+
+// Партиционер, использующий явно заданную партицию сообщения, иначе - хэш ключа
+type explicitPartitioner struct {
+	hash sarama.Partitioner
+}
+
+func newExplicitPartitioner(topic string) sarama.Partitioner {
+	return &explicitPartitioner{hash: sarama.NewHashPartitioner(topic)}
+}
+
+func (p *explicitPartitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if partition, ok := message.Metadata.(explicitPartition); ok {
+		if partition < 0 || int32(partition) >= numPartitions {
+			return 0, fmt.Errorf("партиция %d не найдена, партиций в топике: %d", partition, numPartitions)
+		}
+		return int32(partition), nil
+	}
+	return p.hash.Partition(message, numPartitions)
+}
+
+func (p *explicitPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+// Ключ или значение сообщения из текста или base64
+func bytesFromTextOrBase64(text *string, encoded, field string) (sarama.Encoder, error) {
+	if encoded != "" {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный base64 в %sBase64: %v", field, err)
+		}
+		return sarama.ByteEncoder(data), nil
+	}
+	if text == nil {
+		return nil, nil
+	}
+	return sarama.StringEncoder(*text), nil
+}
+
+// Разбираем строку входных данных в сообщение
+func parseProduceLine(line string, options produceOptions) (*sarama.ProducerMessage, error) {
+	message := &sarama.ProducerMessage{Topic: options.Topic}
+	if options.Partition >= 0 {
+		message.Metadata = explicitPartition(options.Partition)
+	}
+
+	if options.Input == inputJSON {
+		var input producedMessage
+		if err := json.Unmarshal([]byte(line), &input); err != nil {
+			return nil, fmt.Errorf("некорректный JSON: %v", err)
+		}
+		var err error
+		if message.Key, err = bytesFromTextOrBase64(input.Key, input.KeyBase64, "key"); err != nil {
+			return nil, err
+		}
+		if message.Value, err = bytesFromTextOrBase64(input.Value, input.ValueBase64, "value"); err != nil {
+			return nil, err
+		}
+		for _, header := range input.Headers {
+			value, err := bytesFromTextOrBase64(header.Value, header.ValueBase64, "headers.value")
+			if err != nil {
+				return nil, err
+			}
+			recordHeader := sarama.RecordHeader{Key: []byte(header.Key)}
+			if value != nil {
+				if recordHeader.Value, err = value.Encode(); err != nil {
+					return nil, err
+				}
+			}
+			message.Headers = append(message.Headers, recordHeader)
+		}
+		if input.Partition != nil {
+			if *input.Partition < 0 {
+				return nil, fmt.Errorf("некорректная партиция %d", *input.Partition)
+			}
+			message.Metadata = explicitPartition(*input.Partition)
+		}
+		return message, nil
+	}
+
+	if options.KeySeparator != "" {
+		key, value, ok := strings.Cut(line, options.KeySeparator)
+		if !ok {
+			return nil, fmt.Errorf("не найден разделитель ключа %q", options.KeySeparator)
+		}
+		message.Key = sarama.StringEncoder(key)
+		message.Value = sarama.StringEncoder(value)
+		return message, nil
+	}
+	message.Value = sarama.StringEncoder(line)
+	return message, nil
+}
+
+// Настройки producer на основе конфигурации клиента
+func producerConfig(base *sarama.Config, options produceOptions) (*sarama.Config, error) {
+	config := *base
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.Partitioner = newExplicitPartitioner
+
+	if options.Compression != "" {
+		if err := config.Producer.Compression.UnmarshalText([]byte(options.Compression)); err != nil {
+			return nil, fmt.Errorf("неподдерживаемое сжатие %q: должно быть none, gzip, snappy, lz4 или zstd", options.Compression)
+		}
+	}
+
+	switch options.Acks {
+	case "", "all", "-1":
+		config.Producer.RequiredAcks = sarama.WaitForAll
+	case "1":
+		config.Producer.RequiredAcks = sarama.WaitForLocal
+	case "0":
+		config.Producer.RequiredAcks = sarama.NoResponse
+	default:
+		return nil, fmt.Errorf("неподдерживаемое значение acks %q: должно быть all, 1 или 0", options.Acks)
+	}
+
+	if options.Idempotent {
+		if config.Producer.RequiredAcks != sarama.WaitForAll {
+			return nil, fmt.Errorf("идемпотентная запись требует acks all")
+		}
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("некорректная конфигурация producer: %v", err)
+	}
+	return &config, nil
+}
+
+// Записываем сообщения в топик из stdin или файла и выводим партицию и смещение каждой записи
+func produceTopic(client sarama.Client, options produceOptions, output io.Writer) error {
+	switch options.Input {
+	case "", inputLines, inputJSON:
+	default:
+		return fmt.Errorf("неподдерживаемый формат входных данных %q: должен быть lines или json", options.Input)
+	}
+
+	input := io.Reader(os.Stdin)
+	if options.File != "" && options.File != "-" {
+		file, err := os.Open(options.File)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения файла %s: %v", options.File, err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	config, err := producerConfig(client.Config(), options)
+	if err != nil {
+		return err
+	}
+	// Отдельное подключение: настройки producer отличаются от настроек общего клиента
+	producer, err := sarama.NewSyncProducer(viper.GetStringSlice("kafka.broker"), config)
+	if err != nil {
+		return fmt.Errorf("ошибка создания producer: %v", err)
+	}
+	defer producer.Close()
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxProduceLineSize)
+
+	var written, failed int
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" && options.Input == inputJSON {
+			continue
+		}
+		message, err := parseProduceLine(line, options)
+		if err != nil {
+			log.Printf("Строка %d: %v", lineNumber, err)
+			failed++
+			continue
+		}
+		partition, offset, err := producer.SendMessage(message)
+		if err != nil {
+			log.Printf("Строка %d: ошибка записи сообщения: %v", lineNumber, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(output, "%s\t%d\t%d\n", options.Topic, partition, offset)
+		written++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения входных данных: %v", err)
+	}
+
+	log.Printf("Записано сообщений: %d, ошибок: %d", written, failed)
+	if failed > 0 {
+		return fmt.Errorf("не удалось записать сообщений: %d", failed)
+	}
+	return nil
+}
@@ -0,0 +1,557 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+)
+
+// Имена файлов с планами перераспределения партиций в локальной директории reassign.dir
+const (
+	reassignPlanFile   = "expand-cluster-reassignment.json"
+	reassignBackupFile = "backup-expand-cluster-reassignment.json"
+)
+
+// План перераспределения партиций в формате kafka-reassign-partitions.sh,
+// чтобы сохраненные планы можно было использовать и со стандартными инструментами Kafka
+type reassignmentPlan struct {
+	Version    int                     `json:"version"`
+	Partitions []partitionReassignment `json:"partitions"`
+}
+
+type partitionReassignment struct {
+	Topic     string   `json:"topic"`
+	Partition int32    `json:"partition"`
+	Replicas  []int32  `json:"replicas"`
+	LogDirs   []string `json:"log_dirs,omitempty"`
+}
+
+// Группируем партиции плана по топикам
+func (p *reassignmentPlan) byTopic() map[string][]partitionReassignment {
+	result := make(map[string][]partitionReassignment)
+	for _, partition := range p.Partitions {
+		result[partition.Topic] = append(result[partition.Topic], partition)
+	}
+	return result
+}
+
+// Директория на машине оператора, где хранятся планы перераспределения
+func reassignDir() string {
+	dir := viper.GetString("reassign.dir")
+	if dir == "" {
+		dir = "reassign"
+	}
+	return dir
+}
+
+func saveReassignmentPlan(fileName string, plan *reassignmentPlan) error {
+	dir := reassignDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("ошибка создания директории %s: %v", dir, err)
+	}
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка формирования JSON: %v", err)
+	}
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %v", path, err)
+	}
+	log.Printf("План сохранен в %s (партиций: %d)", path, len(plan.Partitions))
+	return nil
+}
+
+func loadReassignmentPlan(fileName string) (*reassignmentPlan, error) {
+	path := filepath.Join(reassignDir(), fileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла %s: %v", path, err)
+	}
+	var plan reassignmentPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON файла %s: %v", path, err)
+	}
+	if len(plan.Partitions) == 0 {
+		return nil, fmt.Errorf("план %s не содержит партиций", path)
+	}
+	return &plan, nil
+}
+
+// Разбираем список брокеров для перераспределения вида "1,2,3".
+// Используется reassign.brokerList, для совместимости со старым конфигом - container.brokerList
+func reassignBrokerList() ([]int32, error) {
+	value := viper.GetString("reassign.brokerList")
+	if value == "" {
+		value = viper.GetString("container.brokerList")
+	}
+	if value == "" {
+		return nil, fmt.Errorf("не задан список брокеров reassign.brokerList")
+	}
+	var brokers []int32
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(item, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный id брокера %q: %v", item, err)
+		}
+		if !slices.Contains(brokers, int32(id)) {
+			brokers = append(brokers, int32(id))
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("список брокеров reassign.brokerList пуст")
+	}
+	return brokers, nil
+}
+
+// Получаем стойки брокеров из метаданных кластера (broker.rack)
+func brokerRacks(client sarama.Client) map[int32]string {
+	racks := make(map[int32]string)
+	for _, broker := range client.Brokers() {
+		racks[broker.ID()] = broker.Rack()
+	}
+	return racks
+}
+
+// Отказываемся от плана, уменьшающего количество стоек у партиций.
+// С reassign.allowRackReduction: true выводится только предупреждение
+func checkRackDiversity(current, proposed *reassignmentPlan, racks map[int32]string) error {
+	reduced := rackDiversityReductions(current, proposed, racks)
+	if len(reduced) == 0 {
+		return nil
+	}
+	for _, partition := range reduced {
+		log.Printf("⚠️ Уменьшается количество стоек партиции %s", partition)
+	}
+	if viper.GetBool("reassign.allowRackReduction") {
+		log.Printf("⚠️ План уменьшает разнообразие стоек для %d партиций (разрешено reassign.allowRackReduction)", len(reduced))
+		return nil
+	}
+	return fmt.Errorf("план уменьшает разнообразие стоек для %d партиций, для применения укажите reassign.allowRackReduction: true", len(reduced))
+}
+
+// Получаем текущее распределение реплик для топиков из метаданных кластера
+func currentAssignment(client sarama.Client, topics []string) (*reassignmentPlan, error) {
+	if err := client.RefreshMetadata(topics...); err != nil {
+		return nil, fmt.Errorf("ошибка обновления метаданных: %v", err)
+	}
+	plan := &reassignmentPlan{Version: 1}
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения партиций топика %s: %v", topic, err)
+		}
+		slices.Sort(partitions)
+		for _, partition := range partitions {
+			replicas, err := client.Replicas(topic, partition)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка получения реплик %s-%d: %v", topic, partition, err)
+			}
+			plan.Partitions = append(plan.Partitions, partitionReassignment{
+				Topic:     topic,
+				Partition: partition,
+				Replicas:  slices.Clone(replicas),
+			})
+		}
+	}
+	return plan, nil
+}
+
+// Распределяем реплики партиций по брокерам так же, как это делает Kafka
+// (AdminUtils.assignReplicasToBrokersRackUnaware): первая реплика идет по кругу
+// со случайного брокера, остальные сдвигаются, чтобы лидеры и реплики распределялись равномерно
+func assignReplicasToBrokers(partitions, replicationFactor int, brokers []int32) ([][]int32, error) {
+	n := len(brokers)
+	if replicationFactor <= 0 {
+		return nil, fmt.Errorf("некорректный фактор репликации %d", replicationFactor)
+	}
+	if replicationFactor > n {
+		return nil, fmt.Errorf("фактор репликации %d больше количества брокеров %d", replicationFactor, n)
+	}
+
+	result := make([][]int32, partitions)
+	startIndex := rand.IntN(n)
+	nextReplicaShift := rand.IntN(n)
+	for partition := 0; partition < partitions; partition++ {
+		if partition > 0 && partition%n == 0 {
+			nextReplicaShift++
+		}
+		firstReplicaIndex := (partition + startIndex) % n
+		replicas := []int32{brokers[firstReplicaIndex]}
+		for j := 0; j < replicationFactor-1; j++ {
+			shift := 1 + (nextReplicaShift+j)%(n-1)
+			replicas = append(replicas, brokers[(firstReplicaIndex+shift)%n])
+		}
+		result[partition] = replicas
+	}
+	return result, nil
+}
+
+// Формируем план полного перераспределения, как --generate в Kafka: для каждого топика
+// реплики заново распределяются по списку брокеров с сохранением фактора репликации
+func proposeAssignment(current *reassignmentPlan, brokers []int32) (*reassignmentPlan, error) {
+	proposed := &reassignmentPlan{Version: 1}
+	byTopic := current.byTopic()
+	topics := make([]string, 0, len(byTopic))
+	for topic := range byTopic {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	for _, topic := range topics {
+		partitions := byTopic[topic]
+		assignment, err := assignReplicasToBrokers(len(partitions), len(partitions[0].Replicas), brokers)
+		if err != nil {
+			return nil, fmt.Errorf("топик %s: %v", topic, err)
+		}
+		for i, partition := range partitions {
+			proposed.Partitions = append(proposed.Partitions, partitionReassignment{
+				Topic:     topic,
+				Partition: partition.Partition,
+				Replicas:  assignment[i],
+			})
+		}
+	}
+	return proposed, nil
+}
+
+// Отправляем контроллеру перераспределение только указанных партиций топика.
+// AlterPartitionReassignments из ClusterAdmin передает реплики для всех партиций 0..N подряд
+// и тем самым затронул бы партиции вне плана, поэтому запрос формируется напрямую
+func alterPartitionReassignments(admin sarama.ClusterAdmin, client sarama.Client, topic string, partitions []partitionReassignment) error {
+	request := &sarama.AlterPartitionReassignmentsRequest{TimeoutMs: 60000}
+	for _, partition := range partitions {
+		request.AddBlock(topic, partition.Partition, partition.Replicas)
+	}
+
+	controller, err := admin.Controller()
+	if err != nil {
+		return fmt.Errorf("ошибка получения контроллера: %v", err)
+	}
+	response, err := controller.AlterPartitionReassignments(request)
+	if err != nil {
+		return err
+	}
+	if response.ErrorCode != sarama.ErrNoError {
+		if response.ErrorMessage != nil {
+			return fmt.Errorf("%v: %s", response.ErrorCode, *response.ErrorMessage)
+		}
+		return response.ErrorCode
+	}
+
+	// Ошибки по партициям sarama не раскрывает, поэтому проверяем, что каждая партиция
+	// перераспределяется или уже имеет реплики из плана
+	partitionIDs := make([]int32, len(partitions))
+	for i, partition := range partitions {
+		partitionIDs[i] = partition.Partition
+	}
+	status, err := admin.ListPartitionReassignments(topic, partitionIDs)
+	if err != nil {
+		return fmt.Errorf("ошибка получения статуса перераспределения: %v", err)
+	}
+	if err := client.RefreshMetadata(topic); err != nil {
+		return fmt.Errorf("ошибка обновления метаданных: %v", err)
+	}
+	var rejected []string
+	for _, partition := range partitions {
+		if _, ok := status[topic][partition.Partition]; ok {
+			continue
+		}
+		replicas, err := client.Replicas(topic, partition.Partition)
+		if err != nil || !slices.Equal(replicas, partition.Replicas) {
+			rejected = append(rejected, strconv.Itoa(int(partition.Partition)))
+		}
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("перераспределение не принято для партиций %s", strings.Join(rejected, ","))
+	}
+	return nil
+}
+
+// Запускаем перераспределение партиций по плану. Контроллеру передаются только партиции плана,
+// реплики которых отличаются от текущих, остальные партиции топика не затрагиваются.
+// При checkRacks план не применяется, если уменьшает количество стоек у партиций
+func executeReassignmentPlan(admin sarama.ClusterAdmin, client sarama.Client, plan *reassignmentPlan, checkRacks bool) error {
+	byTopic := plan.byTopic()
+	topics := make([]string, 0, len(byTopic))
+	for topic := range byTopic {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	current, err := currentAssignment(client, topics)
+	if err != nil {
+		return err
+	}
+	if checkRacks {
+		if err := checkRackDiversity(current, plan, brokerRacks(client)); err != nil {
+			return err
+		}
+	}
+	currentReplicas := make(map[string]map[int32][]int32)
+	for _, partition := range current.Partitions {
+		if currentReplicas[partition.Topic] == nil {
+			currentReplicas[partition.Topic] = make(map[int32][]int32)
+		}
+		currentReplicas[partition.Topic][partition.Partition] = partition.Replicas
+	}
+
+	var failed []string
+	for _, topic := range topics {
+		var changed []partitionReassignment
+		for _, partition := range byTopic[topic] {
+			replicas, ok := currentReplicas[topic][partition.Partition]
+			if !ok {
+				return fmt.Errorf("партиция %s-%d отсутствует в кластере", topic, partition.Partition)
+			}
+			if !slices.Equal(replicas, partition.Replicas) {
+				changed = append(changed, partition)
+			}
+		}
+		if len(changed) == 0 {
+			log.Printf("Топик %s: распределение реплик не изменяется", topic)
+			continue
+		}
+
+		if err := alterPartitionReassignments(admin, client, topic, changed); err != nil {
+			log.Printf("Ошибка запуска перераспределения топика %s: %v", topic, err)
+			failed = append(failed, topic)
+			continue
+		}
+		log.Printf("Топик %s: запущено перераспределение %d партиций", topic, len(changed))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("не удалось запустить перераспределение топиков: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Проверяем состояние перераспределения партиций по плану
+func verifyReassignmentPlan(admin sarama.ClusterAdmin, client sarama.Client, plan *reassignmentPlan) error {
+	byTopic := plan.byTopic()
+	topics := make([]string, 0, len(byTopic))
+	for topic := range byTopic {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	current, err := currentAssignment(client, topics)
+	if err != nil {
+		return err
+	}
+	currentReplicas := make(map[string]map[int32][]int32)
+	for _, partition := range current.Partitions {
+		if currentReplicas[partition.Topic] == nil {
+			currentReplicas[partition.Topic] = make(map[int32][]int32)
+		}
+		currentReplicas[partition.Topic][partition.Partition] = partition.Replicas
+	}
+
+	var completed, inProgress, mismatched int
+	for _, topic := range topics {
+		var partitionIDs []int32
+		for _, partition := range byTopic[topic] {
+			partitionIDs = append(partitionIDs, partition.Partition)
+		}
+		status, err := admin.ListPartitionReassignments(topic, partitionIDs)
+		if err != nil {
+			return fmt.Errorf("ошибка получения статуса перераспределения топика %s: %v", topic, err)
+		}
+
+		for _, partition := range byTopic[topic] {
+			if reassignment, ok := status[topic][partition.Partition]; ok {
+				inProgress++
+				log.Printf("⏳ %s-%d: выполняется (реплики %v, добавляются %v, удаляются %v)",
+					topic, partition.Partition, reassignment.Replicas, reassignment.AddingReplicas, reassignment.RemovingReplicas)
+				continue
+			}
+			replicas := currentReplicas[topic][partition.Partition]
+			if slices.Equal(replicas, partition.Replicas) {
+				completed++
+				log.Printf("✅ %s-%d: завершено %v", topic, partition.Partition, replicas)
+				continue
+			}
+			mismatched++
+			log.Printf("❌ %s-%d: текущие реплики %v не совпадают с планом %v", topic, partition.Partition, replicas, partition.Replicas)
+		}
+	}
+
+	log.Printf("Завершено: %d, выполняется: %d, не совпадает с планом: %d", completed, inProgress, mismatched)
+	if mismatched > 0 {
+		return fmt.Errorf("распределение %d партиций не совпадает с планом", mismatched)
+	}
+	if inProgress > 0 {
+		return fmt.Errorf("перераспределение %d партиций еще выполняется", inProgress)
+	}
+	return nil
+}
+
+func (c *Topic) topicGenerateReassignPart(client sarama.Client, topicList *Topic) error {
+	var topics []string
+	for _, topic := range topicList.Topics {
+		topics = append(topics, topic["topic"])
+	}
+	if len(topics) == 0 {
+		return fmt.Errorf("список топиков пуст")
+	}
+	slices.Sort(topics)
+
+	brokers, err := reassignBrokerList()
+	if err != nil {
+		return err
+	}
+
+	current, err := currentAssignment(client, topics)
+	if err != nil {
+		return err
+	}
+	racks := brokerRacks(client)
+	proposed, err := planMinimalMovement(current, brokers, racks)
+	if err != nil {
+		return err
+	}
+	if err := checkRackDiversity(current, proposed, racks); err != nil {
+		return err
+	}
+	// План в стиле --generate Kafka строится только для сравнения объема перемещений
+	naive, err := proposeAssignment(current, brokers)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for i := range proposed.Partitions {
+		if !slices.Equal(current.Partitions[i].Replicas, proposed.Partitions[i].Replicas) {
+			changed++
+		}
+	}
+	log.Printf("Брокеры для размещения реплик: %v", brokers)
+	log.Printf("Партиций в плане: %d, изменяется распределение: %d", len(proposed.Partitions), changed)
+	log.Printf("Перемещений реплик: %d (при полном перераспределении: %d)",
+		countReplicaMoves(current, proposed), countReplicaMoves(current, naive))
+
+	// Сохраняем текущее распределение для отката и предлагаемый план
+	if err := saveReassignmentPlan(reassignBackupFile, current); err != nil {
+		return err
+	}
+	return saveReassignmentPlan(reassignPlanFile, proposed)
+}
+
+func (c *Topic) topicApplyReassignPart(client sarama.Client) error {
+	plan, err := loadReassignmentPlan(reassignPlanFile)
+	if err != nil {
+		return err
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	return executeReassignmentPlan(admin, client, plan, true)
+}
+
+func (c *Topic) topicVerifyReassignPart(client sarama.Client) error {
+	plan, err := loadReassignmentPlan(reassignPlanFile)
+	if err != nil {
+		return err
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	return verifyReassignmentPlan(admin, client, plan)
+}
+
+func (c *Topic) topicRollbackReassignPart(client sarama.Client) error {
+	plan, err := loadReassignmentPlan(reassignBackupFile)
+	if err != nil {
+		return err
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	// Откат возвращает исходное распределение, поэтому стойки не проверяются
+	return executeReassignmentPlan(admin, client, plan, false)
+}
+
+// Изменяем фактор репликации топиков через механизм перераспределения партиций.
+// План и исходное распределение сохраняются так же, как при -g, поэтому
+// ход выполнения проверяется через -v, а откат выполняется через -r
+func (c *Topic) topicChangeReplicationFactor(client sarama.Client, replicationFactors map[string]int, freeReplicaBrokerId map[string][]int32, replicaBrokerId map[string][]int32) error {
+	if len(replicationFactors) == 0 {
+		log.Printf("Изменение фактора репликации не требуется")
+		return nil
+	}
+
+	topics := make([]string, 0, len(replicationFactors))
+	topicBrokers := make(map[string][]int32, len(replicationFactors))
+	for topic := range replicationFactors {
+		topics = append(topics, topic)
+		topicBrokers[topic] = append(slices.Clone(replicaBrokerId[topic]), freeReplicaBrokerId[topic]...)
+	}
+	slices.Sort(topics)
+
+	current, err := currentAssignment(client, topics)
+	if err != nil {
+		return err
+	}
+	racks := brokerRacks(client)
+	proposed, err := planReplicationFactor(current, replicationFactors, topicBrokers, racks)
+	if err != nil {
+		return err
+	}
+	if err := checkRackDiversity(current, proposed, racks); err != nil {
+		return err
+	}
+
+	for _, topic := range topics {
+		log.Printf("Топик %s: фактор репликации -> %d", topic, replicationFactors[topic])
+	}
+	log.Printf("Перемещений реплик: %d", countReplicaMoves(current, proposed))
+
+	// Сохраняем исходное распределение для отката и план для проверки
+	if err := saveReassignmentPlan(reassignBackupFile, current); err != nil {
+		return err
+	}
+	if err := saveReassignmentPlan(reassignPlanFile, proposed); err != nil {
+		return err
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	return executeReassignmentPlan(admin, client, proposed, true)
+}
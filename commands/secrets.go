@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Параметры конфигурации с учетными данными, в которых допускаются ссылки на секреты
+var secretConfigKeys = []string{
+	"kafka.sasl.password",
+	"kafka.sasl.oauth.clientSecret",
+	"kafka.tls.truststore.password",
+	"kafka.tls.keystore.password",
+	"kafka.tls.keystore.keyPassword",
+}
+
+// Получаем значение секрета по ссылке:
+//
+//	env:NAME      - переменная окружения
+//	file:/path    - содержимое файла без завершающего перевода строки
+//	exec:command  - вывод команды (выполняется через sh -c)
+//
+// Значение без префикса возвращается как есть
+func resolveSecret(value string) (string, error) {
+	kind, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	switch kind {
+	case "env":
+		secret, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("переменная окружения %s не задана", ref)
+		}
+		return secret, nil
+	case "file":
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", fmt.Errorf("ошибка чтения файла секрета: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "exec":
+		var stderr bytes.Buffer
+		command := exec.Command("sh", "-c", ref)
+		command.Stderr = &stderr
+		output, err := command.Output()
+		if err != nil {
+			if message := strings.TrimSpace(stderr.String()); message != "" {
+				return "", fmt.Errorf("ошибка выполнения команды секрета: %v: %s", err, message)
+			}
+			return "", fmt.Errorf("ошибка выполнения команды секрета: %v", err)
+		}
+		return strings.TrimRight(string(output), "\r\n"), nil
+	}
+	return value, nil
+}
+
+// Заменяем ссылки на секреты в параметрах конфигурации их значениями,
+// чтобы подключение к кластеру и генерация параметров клиента использовали реальные значения
+func ResolveConfigSecrets() error {
+	for _, key := range secretConfigKeys {
+		value := viper.GetString(key)
+		if value == "" {
+			continue
+		}
+		secret, err := resolveSecret(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		if secret != value {
+			viper.Set(key, secret)
+		}
+	}
+	return nil
+}
+
+// Длина генерируемого пароля пользователя в байтах (до кодирования base64)
+const generatedPasswordBytes = 24
+
+// Генерируем случайный пароль пользователя
+func generatePassword() (string, error) {
+	buf := make([]byte, generatedPasswordBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации пароля: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Сохраняем сгенерированные пароли пользователей в отдельный YAML файл, доступный только владельцу.
+// Пароли из предыдущих запусков сохраняются, пароли тех же пользователей заменяются
+func writePasswordsFile(filePath string, passwords map[string]string) error {
+	type userPassword struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	}
+	type passwordsFile struct {
+		Users []userPassword `yaml:"users"`
+	}
+
+	var file passwordsFile
+	data, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("ошибка чтения файла %s: %v", filePath, err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("ошибка парсинга YAML файла %s: %v", filePath, err)
+		}
+	}
+
+	written := make(map[string]bool)
+	for i, user := range file.Users {
+		if password, ok := passwords[user.Username]; ok {
+			file.Users[i].Password = password
+			written[user.Username] = true
+		}
+	}
+	usernames := make([]string, 0, len(passwords))
+	for username := range passwords {
+		if !written[username] {
+			usernames = append(usernames, username)
+		}
+	}
+	slices.Sort(usernames)
+	for _, username := range usernames {
+		file.Users = append(file.Users, userPassword{Username: username, Password: passwords[username]})
+	}
+
+	data, err = yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("ошибка формирования YAML: %v", err)
+	}
+	if err := os.WriteFile(filePath, data, 0o600); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %v", filePath, err)
+	}
+	// WriteFile не меняет права существующего файла
+	if err := os.Chmod(filePath, 0o600); err != nil {
+		return fmt.Errorf("ошибка изменения прав файла %s: %v", filePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// Изменения одного топика, необходимые для приведения кластера к описанию в YAML
+type topicPlanEntry struct {
+	Topic  string
+	Exists bool
+	Detail *sarama.TopicDetail
+	Params map[string]interface{}
+
+	// Изменения конфигурации и значения до изменения
+	ConfigChanges map[string]sarama.IncrementalAlterConfigsEntry
+	ConfigBefore  map[string]sarama.ConfigEntry
+
+	CurrentPartitions int32
+	CurrentReplicas   []int
+	DesiredReplicas   int
+
+	// Изменения, которые невозможно выполнить (например, уменьшение партиций)
+	Errors []string
+}
+
+func (e *topicPlanEntry) partitionsIncrease() bool {
+	return e.Exists && e.Detail.NumPartitions > e.CurrentPartitions
+}
+
+func (e *topicPlanEntry) replicationChange() bool {
+	if !e.Exists || e.DesiredReplicas <= 0 {
+		return false
+	}
+	for _, replicas := range e.CurrentReplicas {
+		if replicas != e.DesiredReplicas {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *topicPlanEntry) untouched() bool {
+	return e.Exists && len(e.ConfigChanges) == 0 && !e.partitionsIncrease() && !e.replicationChange() && len(e.Errors) == 0
+}
+
+// Читаем топики из нескольких YAML файлов, один топик может быть описан только в одном файле
+func readTopicsFiles(filePaths []string) (map[string]map[string]interface{}, error) {
+	topics := make(map[string]map[string]interface{})
+	source := make(map[string]string)
+	for _, filePath := range filePaths {
+		topicsConfig, err := readTopicsFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filePath, err)
+		}
+		for topicName, params := range topicsConfig {
+			if previous, ok := source[topicName]; ok {
+				return nil, fmt.Errorf("топик %s описан в нескольких файлах: %s и %s", topicName, previous, filePath)
+			}
+			source[topicName] = filePath
+			topics[topicName] = params
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("не найдено топиков в файлах")
+	}
+	return topics, nil
+}
+
+// Сравниваем описание топиков в YAML с состоянием кластера и формируем план изменений
+func buildTopicPlan(admin sarama.ClusterAdmin, topicsConfig map[string]map[string]interface{}) ([]*topicPlanEntry, error) {
+	topicNames := make([]string, 0, len(topicsConfig))
+	for topicName := range topicsConfig {
+		topicNames = append(topicNames, topicName)
+	}
+	slices.Sort(topicNames)
+
+	metadata, err := admin.DescribeTopics(topicNames)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения метаданных топиков: %v", err)
+	}
+	metadataByTopic := make(map[string]*sarama.TopicMetadata, len(metadata))
+	for _, topicMetadata := range metadata {
+		metadataByTopic[topicMetadata.Name] = topicMetadata
+	}
+
+	var plan []*topicPlanEntry
+	for _, topicName := range topicNames {
+		params := topicsConfig[topicName]
+		detail, err := topicDetailFromParams(params)
+		if err != nil {
+			return nil, fmt.Errorf("топик %s: %v", topicName, err)
+		}
+		entry := &topicPlanEntry{
+			Topic:           topicName,
+			Detail:          detail,
+			Params:          params,
+			DesiredReplicas: int(detail.ReplicationFactor),
+		}
+		plan = append(plan, entry)
+
+		topicMetadata, ok := metadataByTopic[topicName]
+		if !ok || errors.Is(topicMetadata.Err, sarama.ErrUnknownTopicOrPartition) {
+			continue // Топик будет создан
+		}
+		if topicMetadata.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("ошибка получения метаданных топика %s: %v", topicName, topicMetadata.Err)
+		}
+
+		entry.Exists = true
+		entry.CurrentPartitions = int32(len(topicMetadata.Partitions))
+		for _, partition := range topicMetadata.Partitions {
+			entry.CurrentReplicas = append(entry.CurrentReplicas, len(partition.Replicas))
+		}
+
+		entry.ConfigBefore, err = describeTopicConfig(admin, topicName)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения конфигурации топика %s: %v", topicName, err)
+		}
+		entry.ConfigChanges = topicConfigChanges(params, entry.ConfigBefore)
+
+		if detail.NumPartitions > 0 && detail.NumPartitions < entry.CurrentPartitions {
+			entry.Errors = append(entry.Errors, fmt.Sprintf("уменьшение количества партиций с %d до %d не поддерживается Kafka", entry.CurrentPartitions, detail.NumPartitions))
+		}
+	}
+	return plan, nil
+}
+
+// Выводим план изменений топиков и возвращаем количество ошибок в плане
+func printTopicPlan(plan []*topicPlanEntry) int {
+	var create, change, untouched, failed int
+	for _, entry := range plan {
+		switch {
+		case !entry.Exists:
+			create++
+			log.Printf("+ создать %s (партиций: %d, реплик: %d)", entry.Topic, entry.Detail.NumPartitions, entry.Detail.ReplicationFactor)
+			keys := make([]string, 0, len(entry.Detail.ConfigEntries))
+			for key := range entry.Detail.ConfigEntries {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				log.Printf("      %s = %s", key, *entry.Detail.ConfigEntries[key])
+			}
+			continue
+		case entry.untouched():
+			untouched++
+			log.Printf("= без изменений %s", entry.Topic)
+			continue
+		}
+
+		if len(entry.Errors) > 0 {
+			failed++
+			for _, message := range entry.Errors {
+				log.Printf("! ошибка %s: %s", entry.Topic, message)
+			}
+		} else {
+			change++
+		}
+		if len(entry.ConfigChanges) > 0 {
+			log.Printf("~ конфигурация %s", entry.Topic)
+			keys := make([]string, 0, len(entry.ConfigChanges))
+			for key := range entry.ConfigChanges {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				before, ok := entry.ConfigBefore[key]
+				after := "<по умолчанию>"
+				if update := entry.ConfigChanges[key]; update.Operation == sarama.IncrementalAlterConfigsOperationSet {
+					after = *update.Value
+				}
+				log.Printf("      %s: %s -> %s", key, formatConfigValue(before, ok), after)
+			}
+		}
+		if entry.partitionsIncrease() {
+			log.Printf("~ партиции %s: %d -> %d", entry.Topic, entry.CurrentPartitions, entry.Detail.NumPartitions)
+		}
+		if entry.replicationChange() {
+			current := slices.Clone(entry.CurrentReplicas)
+			slices.Sort(current)
+			log.Printf("~ фактор репликации %s: %v -> %d", entry.Topic, slices.Compact(current), entry.DesiredReplicas)
+		}
+	}
+	log.Printf("План: создать %d, изменить %d, без изменений %d, ошибок %d", create, change, untouched, failed)
+	return failed
+}
+
+func (c *Topic) topicPlan(client sarama.Client, filePaths []string) error {
+	topicsConfig, err := readTopicsFiles(filePaths)
+	if err != nil {
+		return err
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	plan, err := buildTopicPlan(admin, topicsConfig)
+	if err != nil {
+		return err
+	}
+	if failed := printTopicPlan(plan); failed > 0 {
+		return fmt.Errorf("план содержит %d топиков с невыполнимыми изменениями", failed)
+	}
+	return nil
+}
+
+func (c *Topic) topicApply(client sarama.Client, filePaths []string, brokerIDs []int32) error {
+	topicsConfig, err := readTopicsFiles(filePaths)
+	if err != nil {
+		return err
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	plan, err := buildTopicPlan(admin, topicsConfig)
+	if err != nil {
+		return err
+	}
+	if failed := printTopicPlan(plan); failed > 0 {
+		return fmt.Errorf("план содержит %d топиков с невыполнимыми изменениями, применение отменено", failed)
+	}
+
+	// Выполняем план: создание, конфигурация, партиции, затем фактор репликации,
+	// чтобы новые партиции тоже получили нужное количество реплик
+	var failed []string
+	replicationFactors := make(map[string]int)
+	for _, entry := range plan {
+		if !entry.Exists {
+			if err := admin.CreateTopic(entry.Topic, entry.Detail, false); err != nil {
+				log.Printf("Ошибка создания топика %s: %v", entry.Topic, err)
+				failed = append(failed, entry.Topic)
+				continue
+			}
+			log.Printf("Топик %s успешно создан", entry.Topic)
+			continue
+		}
+		if entry.untouched() {
+			continue
+		}
+		if _, err := alterTopicConfig(admin, entry.Topic, entry.Params, entry.ConfigBefore); err != nil {
+			log.Printf("Ошибка при изменении топика %s: %v", entry.Topic, err)
+			failed = append(failed, entry.Topic)
+			continue
+		}
+		if entry.partitionsIncrease() {
+			if _, err := increaseTopicPartitions(admin, entry.Topic, entry.Params, entry.ConfigBefore); err != nil {
+				log.Printf("Ошибка при изменении количества партиций топика %s: %v", entry.Topic, err)
+				failed = append(failed, entry.Topic)
+				continue
+			}
+		}
+		if entry.replicationChange() {
+			replicationFactors[entry.Topic] = entry.DesiredReplicas
+		}
+	}
+
+	if len(replicationFactors) > 0 {
+		// Для изменения фактора репликации доступны все брокеры кластера
+		replicaBrokerId := make(map[string][]int32, len(replicationFactors))
+		for topic := range replicationFactors {
+			replicaBrokerId[topic] = brokerIDs
+		}
+		if err := c.topicChangeReplicationFactor(client, replicationFactors, nil, replicaBrokerId); err != nil {
+			log.Printf("Ошибка изменения фактора репликации: %v", err)
+			for topic := range replicationFactors {
+				failed = append(failed, topic)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return fmt.Errorf("не удалось применить изменения для топиков: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// ACL, привязанный к ресурсу. Используется как ключ при сравнении ACL из YAML и кластера
+type aclBinding struct {
+	sarama.Resource
+	sarama.Acl
+}
+
+func (b aclBinding) String() string {
+	return fmt.Sprintf("%s %s %s на %s:%s (%s, host %s)",
+		b.Principal, b.PermissionType.String(), b.Operation.String(),
+		b.ResourceType.String(), b.ResourceName, b.ResourcePatternType.String(), b.Host)
+}
+
+// Фильтр, совпадающий ровно с одним ACL
+func (b aclBinding) filter() sarama.AclFilter {
+	return sarama.AclFilter{
+		ResourceType:              b.ResourceType,
+		ResourceName:              &b.ResourceName,
+		ResourcePatternTypeFilter: b.ResourcePatternType,
+		Principal:                 &b.Principal,
+		Host:                      &b.Host,
+		Operation:                 b.Operation,
+		PermissionType:            b.PermissionType,
+	}
+}
+
+// Удаляем ACL и проверяем ошибки брокера по совпавшим ACL
+func deleteAcl(admin sarama.ClusterAdmin, binding aclBinding) error {
+	matching, err := admin.DeleteACL(binding.filter(), false)
+	if err != nil {
+		return err
+	}
+	for _, acl := range matching {
+		if acl.Err != sarama.ErrNoError {
+			if acl.ErrMsg != nil {
+				return fmt.Errorf("%v: %s", acl.Err, *acl.ErrMsg)
+			}
+			return acl.Err
+		}
+	}
+	return nil
+}
+
+func sortAclBindings(bindings []aclBinding) {
+	slices.SortFunc(bindings, func(a, b aclBinding) int {
+		return strings.Compare(a.String(), b.String())
+	})
+}
+
+// Сравниваем ACL пользователя из YAML с ACL принципала в кластере
+func diffUserAcls(admin sarama.ClusterAdmin, user userConfig) (add, remove []aclBinding, err error) {
+	principal := userPrincipal(user.Username)
+
+	declared := make(map[aclBinding]struct{})
+	for _, aclCfg := range user.Acls {
+		resource, acl, err := aclCfg.toSarama(principal)
+		if err != nil {
+			return nil, nil, fmt.Errorf("некорректный ACL: %v", err)
+		}
+		declared[aclBinding{Resource: resource, Acl: acl}] = struct{}{}
+	}
+
+	resourceAcls, err := listAcls(admin, principal)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения списка ACL: %v", err)
+	}
+	existing := make(map[aclBinding]struct{})
+	for _, resourceAcl := range resourceAcls {
+		for _, acl := range resourceAcl.Acls {
+			existing[aclBinding{Resource: resourceAcl.Resource, Acl: *acl}] = struct{}{}
+		}
+	}
+
+	for binding := range declared {
+		if _, ok := existing[binding]; !ok {
+			add = append(add, binding)
+		}
+	}
+	for binding := range existing {
+		if _, ok := declared[binding]; !ok {
+			remove = append(remove, binding)
+		}
+	}
+	sortAclBindings(add)
+	sortAclBindings(remove)
+	return add, remove, nil
+}
+
+// Приводим ACL пользователей к описанию в YAML: недостающие ACL создаются,
+// лишние выводятся и удаляются только при prune
+func (u *User) userReconcileAcl(client sarama.Client, filePath string, prune bool) error {
+	users, err := readUsersFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	// Создаем админ-клиент
+	admin, err := newClusterAdmin(client)
+	if err != nil {
+		log.Printf("Ошибка создания админ-клиента: %v", err)
+		return err
+	}
+	defer admin.Close()
+
+	var failed []string
+	var added, removed, pending int
+	for _, user := range users {
+		if user.Username == "" {
+			log.Printf("Пропуск пользователя из-за отсутствия имени")
+			continue
+		}
+
+		add, remove, err := diffUserAcls(admin, user)
+		if err != nil {
+			log.Printf("Пользователь %s: %v", user.Username, err)
+			failed = append(failed, user.Username)
+			continue
+		}
+		if len(add) == 0 && len(remove) == 0 {
+			log.Printf("= ACL пользователя %s соответствуют описанию", user.Username)
+			continue
+		}
+		for _, binding := range add {
+			log.Printf("+ %s", binding)
+		}
+		for _, binding := range remove {
+			log.Printf("- %s", binding)
+		}
+
+		if len(add) > 0 {
+			creations := make([]*sarama.AclCreation, 0, len(add))
+			for _, binding := range add {
+				creations = append(creations, &sarama.AclCreation{Resource: binding.Resource, Acl: binding.Acl})
+			}
+			errs, err := createAcls(admin, creations)
+			if err != nil {
+				log.Printf("Ошибка добавления ACL для пользователя %s: %v", user.Username, err)
+				failed = append(failed, user.Username)
+				continue
+			}
+			for i, binding := range add {
+				if errs[i] != nil {
+					log.Printf("Ошибка добавления ACL %s: %v", binding, errs[i])
+					failed = append(failed, user.Username)
+					continue
+				}
+				added++
+			}
+		}
+
+		if !prune {
+			pending += len(remove)
+			continue
+		}
+		for _, binding := range remove {
+			if err := deleteAcl(admin, binding); err != nil {
+				log.Printf("Ошибка удаления ACL %s: %v", binding, err)
+				failed = append(failed, user.Username)
+				continue
+			}
+			removed++
+		}
+	}
+
+	log.Printf("ACL добавлено: %d, удалено: %d", added, removed)
+	if pending > 0 {
+		log.Printf("⚠️ Не описанных в файле ACL: %d, для удаления запустите с --prune", pending)
+	}
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return fmt.Errorf("не удалось привести ACL пользователей к описанию: %s", strings.Join(slices.Compact(failed), ", "))
+	}
+	return nil
+}
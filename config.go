@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+)
+
+// Загружаем config.yaml и применяем профиль кластера.
+// Профиль clusters.<name> имеет ту же структуру, что и корень конфига (kafka, reassign, ...),
+// и накладывается поверх него, поэтому общие настройки можно оставить в корне.
+// Любой параметр можно переопределить переменной окружения с префиксом KAFKAMAP_,
+// например KAFKAMAP_KAFKA_SASL_PASSWORD для kafka.sasl.password
+func loadConfig(configFile string) (string, error) {
+	viper.SetConfigFile(configFile)
+	viper.SetEnvPrefix("KAFKAMAP")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	if err := viper.ReadInConfig(); err != nil {
+		return "", fmt.Errorf("ошибка чтения файла конфигурации %s: %v", configFile, err)
+	}
+
+	profiles := viper.GetStringMap("clusters")
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	// Профиль выбирается флагом --cluster, переменной KAFKAMAP_CLUSTER или ключом cluster в конфиге
+	cluster := viper.GetString("cluster")
+	if cluster == "" {
+		if len(names) > 0 && len(viper.GetStringSlice("kafka.broker")) == 0 {
+			return "", fmt.Errorf("не выбран профиль кластера, укажите --cluster: %s", strings.Join(names, ", "))
+		}
+		return "", nil
+	}
+
+	profile, ok := profiles[strings.ToLower(cluster)].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("профиль кластера %q не найден в %s, доступные профили: %s", cluster, configFile, strings.Join(names, ", "))
+	}
+	if err := viper.MergeConfigMap(profile); err != nil {
+		return "", fmt.Errorf("ошибка применения профиля кластера %q: %v", cluster, err)
+	}
+	return cluster, nil
+}
+
+// Выводим активный профиль кластера при запуске
+func logActiveProfile(configFile, cluster string) {
+	if cluster == "" {
+		log.Printf("Конфигурация: %s, профиль кластера не выбран, используется секция kafka", configFile)
+		return
+	}
+	log.Printf("Конфигурация: %s, активный профиль кластера: %s", configFile, cluster)
+}
+
+// Получаем ID кластера из метаданных контроллера.
+// ClusterAdmin.DescribeCluster не возвращает ID кластера, поэтому запрос метаданных отправляется напрямую
+func fetchClusterID(client sarama.Client, version sarama.KafkaVersion) (string, error) {
+	controller, err := client.Controller()
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения контроллера: %v", err)
+	}
+	response, err := controller.GetMetadata(sarama.NewMetadataRequest(version, nil))
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения метаданных кластера: %v", err)
+	}
+	if response.ClusterID == nil || *response.ClusterID == "" {
+		return "", fmt.Errorf("брокер не вернул ID кластера")
+	}
+	return *response.ClusterID, nil
+}
+
+// Сверяем ID кластера с kafka.clusterId из профиля, чтобы устаревший конфиг
+// не направил изменяющую команду на другой кластер
+func verifyClusterID(client sarama.Client, version sarama.KafkaVersion) error {
+	expected := viper.GetString("kafka.clusterId")
+	actual, err := fetchClusterID(client, version)
+	if err != nil {
+		if expected == "" {
+			log.Printf("Не удалось получить ID кластера: %v", err)
+			return nil
+		}
+		return fmt.Errorf("не удалось проверить ID кластера %s: %v", expected, err)
+	}
+	log.Printf("ID кластера: %s", actual)
+	if expected != "" && expected != actual {
+		return fmt.Errorf("ID кластера %s не совпадает с ожидаемым kafka.clusterId %s", actual, expected)
+	}
+	return nil
+}
+
+// Версии TLS, допустимые в kafka.tls.minVersion
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Формируем tls.Config из секции kafka.tls: CA для проверки брокеров,
+// сертификат и ключ клиента для mTLS, имя сервера и минимальная версия TLS
+func newTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         viper.GetString("kafka.tls.serverName"),
+		InsecureSkipVerify: viper.GetBool("kafka.tls.insecureSkipVerify"),
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if minVersion := viper.GetString("kafka.tls.minVersion"); minVersion != "" {
+		version, ok := tlsVersions[strings.TrimPrefix(strings.ToUpper(minVersion), "TLSV")]
+		if !ok {
+			return nil, fmt.Errorf("неподдерживаемая версия TLS %q в kafka.tls.minVersion: должна быть 1.0, 1.1, 1.2 или 1.3", minVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if caFile := viper.GetString("kafka.tls.caFile"); caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения kafka.tls.caFile: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("в файле %s не найдено PEM сертификатов", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile := viper.GetString("kafka.tls.certFile")
+	keyFile := viper.GetString("kafka.tls.keyFile")
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("для mTLS необходимо указать kafka.tls.certFile и kafka.tls.keyFile")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки сертификата клиента: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if tlsConfig.InsecureSkipVerify {
+		log.Printf("⚠️ Проверка сертификатов брокеров отключена (kafka.tls.insecureSkipVerify)")
+	}
+	return tlsConfig, nil
+}
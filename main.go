@@ -5,9 +5,11 @@ import (
 	"crypto/sha512"
 	"kafkamap/commands"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/spf13/pflag"
@@ -30,12 +32,50 @@ func main() {
 	topicsFile := pflag.StringP("file", "f", "", "Путь к файлу со списком существующих топиков")
 	topicDelete := pflag.StringP("topicDelete", "", "", "Удалить топик по имени: --topicDelete my_topic")
 	topicDeleteFile := pflag.StringP("topicDeleteFile", "", "", "Удалить топики из файла: --topicDeleteFile /path/to/topics.txt")
+	topicDeleteGroupsFlag := pflag.BoolP("topicDeleteGroups", "", false, "Удалить группы потребителей без активных участников, которые читали только удаляемые топики (используется с --topicDelete, --topicDeleteFile)")
 	createTopicFile := pflag.StringP("createTopic", "", "", "Создать топик, используется ключ и путь до yaml файла: --createTopic /topics/test.yaml")
 	changeTopicFile := pflag.StringP("changeTopic", "", "", "Изменить топик, используется ключ и путь до yaml файла: --changeTopic /topics/test.yaml")
 	createUserFile := pflag.StringP("createUser", "", "", "Создать пользователя, используется ключ и путь до yaml файла: --createUser /users/test.yaml")
+	passwordFile := pflag.StringP("passwordFile", "", "", "Файл для сгенерированных паролей пользователей, создается с правами 0600 (используется с --createUser): --passwordFile /secure/passwords.yaml")
 	createUserAclFile := pflag.StringP("createUserAcl", "", "", "Добавить ACL для пользователя, используется ключ и путь до yaml файла: --createUserAcl /users/test.yaml")
-	aclList := pflag.StringP("aclList", "", "", "Вывести список ACL для пользователя, используется ключ и имя пользователя: --aclList test")
-	// whoTopicPart := pflag.StringP("whoTopicPart", "", "", "Вывести список партиций топиков, используется ключ и путь до yaml файла: --whoTopicPart /topics/test.yaml")
+	reconcileUserAclFile := pflag.StringP("reconcileUserAcl", "", "", "Привести ACL пользователей к описанию в yaml файле, используется ключ и путь до yaml файла: --reconcileUserAcl /users/test.yaml")
+	pruneFlag := pflag.BoolP("prune", "", false, "Удалять ACL, не описанные в yaml файле (используется с --reconcileUserAcl)")
+	exportDir := pflag.StringP("export", "", "", "Выгрузить топики и пользователей кластера в yaml файлы, используется ключ и путь до директории: --export /backup")
+	exportTopicRegex := pflag.StringP("exportTopicRegex", "", "", "Выгружать только топики, подходящие под регулярное выражение (используется с --export)")
+	exportPrincipal := pflag.StringP("exportPrincipal", "", "", "Выгружать только указанного пользователя (используется с --export)")
+	aclList := pflag.StringP("aclList", "", "", "Вывести список ACL для пользователя, используется ключ и имя пользователя: --aclList test, без имени выводятся ACL всех пользователей")
+	pflag.Lookup("aclList").NoOptDefVal = "*"
+	planFiles := pflag.StringSliceP("plan", "", nil, "Показать изменения топиков относительно кластера, используется ключ и пути до yaml файлов: --plan /topics/a.yaml,/topics/b.yaml")
+	applyFiles := pflag.StringSliceP("apply", "", nil, "Привести топики кластера к описанию в yaml файлах, используется ключ и пути до yaml файлов: --apply /topics/a.yaml,/topics/b.yaml")
+	changeReplicationFactorFile := pflag.StringP("changeReplicationFactor", "", "", "Изменить фактор репликации топиков до значения replicas, используется ключ и путь до yaml файла: --changeReplicationFactor /topics/test.yaml")
+	groupListFlag := pflag.BoolP("groupList", "", false, "Вывести список групп потребителей с состоянием и суммарным отставанием, фильтры: --group, --topic")
+	groupDescribeFlag := pflag.BoolP("groupDescribe", "", false, "Вывести участников, смещения и отставание групп потребителей по партициям, фильтры: --group, --topic")
+	groupPattern := pflag.StringP("group", "", "", "Группа потребителей или шаблон имени групп: --group 'orders-*'")
+	topicPattern := pflag.StringP("topic", "", "", "Топик или шаблон имени топиков, для --resetOffsets можно указать партиции: --topic 'orders.*', --topic orders:0,1")
+	resetOffsetsMode := pflag.StringP("resetOffsets", "", "", "Сбросить смещения группы --group: to-earliest, to-latest, to-offset, shift-by, to-datetime, by-duration, from-file (значение в --resetValue)")
+	resetValue := pflag.StringP("resetValue", "", "", "Значение для --resetOffsets: смещение, сдвиг, дата 2006-01-02T15:04:05, длительность 1h30m или путь к CSV файлу topic,partition,offset")
+	executeFlag := pflag.BoolP("execute", "", false, "Применить сброс смещений (используется с --resetOffsets), без флага выводится только план")
+	groupDeleteFlag := pflag.BoolP("groupDelete", "", false, "Удалить группы потребителей без активных участников по имени или шаблону --group")
+	groupDeleteOffsetsFlag := pflag.BoolP("groupDeleteOffsets", "", false, "Удалить зафиксированные смещения группы --group по топику --topic")
+	consumeTopic := pflag.StringP("consume", "", "", "Прочитать сообщения топика: --consume orders")
+	partitionFlag := pflag.Int32P("partition", "", -1, "Партиция для чтения или записи (используется с --consume и --produce), по умолчанию все партиции / по ключу")
+	offsetFlag := pflag.StringP("offset", "", "earliest", "Начальная позиция чтения: earliest, latest, смещение или -N для N последних сообщений партиции (используется с --consume)")
+	fromTimeFlag := pflag.StringP("fromTime", "", "", "Читать сообщения начиная с даты 2006-01-02T15:04:05[Z07:00] (используется с --consume)")
+	limitFlag := pflag.IntP("limit", "", 0, "Максимальное количество сообщений (используется с --consume), 0 - без ограничения")
+	followFlag := pflag.BoolP("follow", "", false, "Продолжать читать новые сообщения до прерывания (используется с --consume)")
+	produceTopic := pflag.StringP("produce", "", "", "Записать сообщения в топик из stdin или --produceFile, выводятся партиция и смещение каждой записи: --produce orders")
+	produceFile := pflag.StringP("produceFile", "", "", "Файл с сообщениями для --produce, по умолчанию stdin")
+	inputFlag := pflag.StringP("inputFormat", "", "lines", "Формат входных данных --produce: lines (строка - сообщение) или json (JSON lines с полями key, value, keyBase64, valueBase64, headers, partition)")
+	keySeparatorFlag := pflag.StringP("keySeparator", "", "", "Разделитель ключа и значения в строке для --inputFormat lines, без разделителя сообщения пишутся без ключа")
+	compressionFlag := pflag.StringP("compression", "", "none", "Сжатие сообщений для --produce: none, gzip, snappy, lz4 или zstd")
+	acksFlag := pflag.StringP("acks", "", "all", "Подтверждение записи для --produce: all, 1 или 0")
+	idempotentFlag := pflag.BoolP("idempotent", "", false, "Идемпотентная запись для --produce (требует --acks all)")
+	outputFlag := pflag.StringP("output", "", "plain", "Формат вывода сообщений: plain, json (JSON lines с ключом, заголовками, партицией, смещением и временем) или hex")
+	printClientConfigFile := pflag.StringP("printClientConfig", "", "", "Вывести параметры клиента Kafka (.properties) для других инструментов Kafka, без пути выводятся в stdout: --printClientConfig=/tmp/client.properties")
+	pflag.Lookup("printClientConfig").NoOptDefVal = "-"
+	kafkaTool := pflag.StringP("kafkaTool", "", "", "Выполнить инструмент Kafka через исполнитель из секции executor с параметрами клиента профиля, аргументы указываются после --: --kafkaTool kafka-consumer-groups.sh -- --list")
+	configFile := pflag.StringP("config", "", "config.yaml", "Путь к файлу конфигурации: --config /etc/kafkamap/config.yaml")
+	pflag.StringP("cluster", "", "", "Профиль кластера из секции clusters файла конфигурации: --cluster prod (также KAFKAMAP_CLUSTER)")
 
 	// Парсим флаги
 	pflag.Parse()
@@ -45,9 +85,24 @@ func main() {
 		os.Exit(0)
 	}
 
-	viper.SetConfigFile("config.yaml")
-	if err := viper.ReadInConfig(); err != nil {
-		log.Fatalf("Error reading config file: %v", err)
+	if err := viper.BindPFlag("cluster", pflag.Lookup("cluster")); err != nil {
+		log.Fatalf("Ошибка привязки флага --cluster: %v", err)
+	}
+	cluster, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	logActiveProfile(*configFile, cluster)
+	if err := commands.ResolveConfigSecrets(); err != nil {
+		log.Fatalf("Ошибка получения секрета: %v", err)
+	}
+
+	// Параметры клиента формируются из конфигурации, подключение к кластеру не требуется
+	if *printClientConfigFile != "" {
+		if err := commands.NewCommandKafka().PrintClientConfig(*printClientConfigFile); err != nil {
+			log.Fatalf("Ошибка формирования параметров клиента: %v", err)
+		}
+		os.Exit(0)
 	}
 
 	config := sarama.NewConfig()
@@ -61,6 +116,13 @@ func main() {
 
 	// Указываем протокол безопасности
 	config.Net.TLS.Enable = viper.GetBool("kafka.tls.enabled")
+	if config.Net.TLS.Enable {
+		tlsConfig, err := newTLSConfig()
+		if err != nil {
+			log.Fatalf("Ошибка настройки TLS: %v", err)
+		}
+		config.Net.TLS.Config = tlsConfig
+	}
 	config.Net.SASL.Handshake = viper.GetBool("kafka.sasl.handshake")
 
 	// Таймауты для соединения с брокером клиентом
@@ -94,13 +156,57 @@ func main() {
 			return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
 		}
 		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+	case "OAUTHBEARER":
+		log.Printf("Настройка OAUTHBEARER аутентификации")
+		tokenEndpoint := viper.GetString("kafka.sasl.oauth.tokenEndpoint")
+		if tokenEndpoint == "" {
+			log.Fatalf("Для OAUTHBEARER необходимо указать kafka.sasl.oauth.tokenEndpoint")
+		}
+		config.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		config.Net.SASL.TokenProvider = &oauthTokenProvider{
+			TokenEndpoint: tokenEndpoint,
+			ClientID:      viper.GetString("kafka.sasl.oauth.clientId"),
+			ClientSecret:  viper.GetString("kafka.sasl.oauth.clientSecret"),
+			Scopes:        viper.GetStringSlice("kafka.sasl.oauth.scopes"),
+			Extensions:    viper.GetStringMapString("kafka.sasl.oauth.extensions"),
+			HTTPClient:    &http.Client{Timeout: 30 * time.Second},
+		}
+	case "GSSAPI":
+		log.Printf("Настройка GSSAPI (Kerberos) аутентификации")
+		config.Net.SASL.Mechanism = sarama.SASLTypeGSSAPI
+		gssapi := sarama.GSSAPIConfig{
+			ServiceName:        viper.GetString("kafka.sasl.gssapi.serviceName"),
+			Realm:              viper.GetString("kafka.sasl.gssapi.realm"),
+			Username:           viper.GetString("kafka.sasl.gssapi.username"),
+			KerberosConfigPath: viper.GetString("kafka.sasl.gssapi.kerberosConfig"),
+			DisablePAFXFAST:    viper.GetBool("kafka.sasl.gssapi.disablePAFXFAST"),
+		}
+		if gssapi.ServiceName == "" {
+			gssapi.ServiceName = "kafka"
+		}
+		if gssapi.KerberosConfigPath == "" {
+			gssapi.KerberosConfigPath = "/etc/krb5.conf"
+		}
+		// Аутентификация по keytab или по кэшу учетных данных (kinit)
+		switch {
+		case viper.GetString("kafka.sasl.gssapi.keytab") != "":
+			gssapi.AuthType = sarama.KRB5_KEYTAB_AUTH
+			gssapi.KeyTabPath = viper.GetString("kafka.sasl.gssapi.keytab")
+		case viper.GetString("kafka.sasl.gssapi.ccache") != "":
+			gssapi.AuthType = sarama.KRB5_CCACHE_AUTH
+			gssapi.CCachePath = viper.GetString("kafka.sasl.gssapi.ccache")
+		default:
+			log.Fatalf("Для GSSAPI необходимо указать kafka.sasl.gssapi.keytab или kafka.sasl.gssapi.ccache")
+		}
+		config.Net.SASL.GSSAPI = gssapi
+		log.Printf("GSSAPI конфигурация: ServiceName=%s, Realm=%s, Username=%s",
+			gssapi.ServiceName, gssapi.Realm, gssapi.Username)
 	default:
-		log.Fatalf("Неподдерживаемый механизм SASL \"%s\": должен быть \"PLAIN\", \"SCRAM-SHA-256\" или \"SCRAM-SHA-512\"",
+		log.Fatalf("Неподдерживаемый механизм SASL \"%s\": должен быть \"PLAIN\", \"SCRAM-SHA-256\", \"SCRAM-SHA-512\", \"OAUTHBEARER\" или \"GSSAPI\"",
 			viper.GetString("kafka.sasl.mechanism"))
 	}
 
 	var client sarama.Client
-	var err error
 
 	// Функция для создания клиента
 	createClient := func() error {
@@ -132,16 +238,33 @@ func main() {
 		}
 	}
 
+	// Изменяющие команды выполняются только при совпадении ID кластера с kafka.clusterId
+	mutating := *rollbackFlag || *applyFlag || *createTopicFile != "" || *changeTopicFile != "" ||
+		*topicDelete != "" || *topicDeleteFile != "" || *createUserFile != "" || *createUserAclFile != "" ||
+		*reconcileUserAclFile != "" || len(*applyFiles) > 0 || *changeReplicationFactorFile != "" ||
+		(*resetOffsetsMode != "" && *executeFlag) || *groupDeleteFlag || *groupDeleteOffsetsFlag ||
+		*produceTopic != "" || *kafkaTool != ""
+	if err := verifyClusterID(client, config.Version); err != nil {
+		if mutating {
+			client.Close()
+			log.Fatalf("❌ Изменяющие команды отменены: %v", err)
+		}
+		log.Printf("⚠️ %v", err)
+	}
+
 	cmd := commands.NewCommandKafka()
+	exitCode := 0
 
 	// Выполняем команды в зависимости от флагов
 	if *rollbackFlag {
-		if err := cmd.TopicRollbackReassignPart(); err != nil {
+		if err := cmd.TopicRollbackReassignPart(client); err != nil {
 			log.Printf("Ошибка отката перераспределения партиций топиков: %v", err)
+			exitCode = 1
+		} else {
+			log.Printf("==========================================================================")
+			log.Printf("✅ Задача по откату перераспределения партиций топиков, успешно выполнена!")
+			log.Printf("==========================================================================")
 		}
-		log.Printf("==========================================================================")
-		log.Printf("✅ Задача по откату перераспределения партиций топиков, успешно выполнена!")
-		log.Printf("==========================================================================")
 	}
 
 	if *generateFlag {
@@ -150,6 +273,7 @@ func main() {
 			log.Printf("❌ Задача генерации файлов с топиками для перераспределения партиций, не выполнена!")
 			log.Printf("Ошибка: %v", err)
 			log.Printf("============================================================================")
+			exitCode = 1
 		} else {
 			log.Printf("=========================================================================")
 			log.Printf("✅ Файлы с топиками для перераспределения партиций успешно сгенерированы!")
@@ -158,28 +282,34 @@ func main() {
 	}
 
 	if *applyFlag {
-		if err := cmd.TopicApplyReassignPart(); err != nil {
+		if err := cmd.TopicApplyReassignPart(client); err != nil {
 			log.Printf("Ошибка применения перераспределение партиций топиков: %v", err)
+			exitCode = 1
+		} else {
+			log.Printf("===================================================================")
+			log.Printf("✅ Задача по перераспределению партиций топиков, успешно запущена!")
+			log.Printf("===================================================================")
 		}
-		log.Printf("===================================================================")
-		log.Printf("✅ Задача по перераспределению партиций топиков, успешно запущена!")
-		log.Printf("===================================================================")
 	}
 
 	if *verifyFlag {
-		if err := cmd.TopicVerifyReassignPart(); err != nil {
+		if err := cmd.TopicVerifyReassignPart(client); err != nil {
 			log.Printf("Ошибка проверки перераспределения партиций топиков: %v", err)
+			exitCode = 1
+		} else {
+			log.Printf("============================================================================")
+			log.Printf("✅ Задача по проверке перераспределения партиций топиков, успешно выполнена!")
+			log.Printf("============================================================================")
 		}
-		log.Printf("============================================================================")
-		log.Printf("✅ Задача по проверке перераспределения партиций топиков, успешно выполнена!")
-		log.Printf("============================================================================")
 	}
 
 	if *createTopicFile != "" {
-		if err := cmd.TopicCreate(*createTopicFile); err != nil {
+		if err := cmd.TopicCreate(client, *createTopicFile); err != nil {
 			log.Printf("============================================================================")
 			log.Printf("❌ Задача по созданию топика, не выполнена!")
+			log.Printf("Ошибка: %v", err)
 			log.Printf("============================================================================")
+			exitCode = 1
 		} else {
 			log.Printf("============================================================================")
 			log.Printf("✅ Задача по созданию топика, успешно выполнена!")
@@ -188,15 +318,17 @@ func main() {
 	}
 
 	if *changeTopicFile != "" {
-		if err := cmd.TopicChange(*changeTopicFile); err != nil {
+		if err := cmd.TopicChange(client, *changeTopicFile); err != nil {
 			log.Printf("Ошибка при изменении топика: %v", err)
+			exitCode = 1
 		}
 	}
 
 	if *topicDelete != "" {
 		// Удаление одного топика
-		if err := cmd.TopicDelete(*topicDelete, ""); err != nil {
+		if err := cmd.TopicDelete(client, *topicDelete, "", *topicDeleteGroupsFlag); err != nil {
 			log.Printf("Ошибка при удалении топика: %v", err)
+			exitCode = 1
 		} else {
 			log.Printf("============================================================================")
 			log.Printf("✅ Задача по удалению топика, успешно выполнена!")
@@ -205,8 +337,9 @@ func main() {
 	}
 
 	if *topicDeleteFile != "" {
-		if err := cmd.TopicDelete("", *topicDeleteFile); err != nil {
+		if err := cmd.TopicDelete(client, "", *topicDeleteFile, *topicDeleteGroupsFlag); err != nil {
 			log.Printf("Ошибка при удалении топиков из файла: %v", err)
+			exitCode = 1
 		} else {
 			log.Printf("============================================================================")
 			log.Printf("✅ Задача по удалению топиков из файла, успешно выполнена!")
@@ -215,37 +348,142 @@ func main() {
 	}
 
 	if *createUserFile != "" {
-		if err := cmd.UserCreate(*createUserFile); err != nil {
+		if err := cmd.UserCreate(client, *createUserFile, *passwordFile); err != nil {
 			log.Printf("Ошибка при создании пользователя: %v", err)
+			exitCode = 1
 		}
 	}
 
 	if *createUserAclFile != "" {
-		if err := cmd.UserAddAcl(*createUserAclFile); err != nil {
+		if err := cmd.UserAddAcl(client, *createUserAclFile); err != nil {
 			log.Printf("Ошибка при добавлении ACL для пользователя: %v", err)
+			exitCode = 1
+		}
+	}
+
+	if *reconcileUserAclFile != "" {
+		if err := cmd.UserReconcileAcl(client, *reconcileUserAclFile, *pruneFlag); err != nil {
+			log.Printf("Ошибка при приведении ACL пользователей к описанию: %v", err)
+			exitCode = 1
+		}
+	}
+
+	if *exportDir != "" {
+		if err := cmd.Export(client, *exportDir, *exportTopicRegex, *exportPrincipal); err != nil {
+			log.Printf("Ошибка выгрузки состояния кластера: %v", err)
+			exitCode = 1
 		}
 	}
 
 	if *aclList != "" {
-		if err := cmd.AclList(*aclList); err != nil {
+		if err := cmd.AclList(client, *aclList); err != nil {
 			log.Printf("Ошибка при выводе ACL для пользователя: %v", err)
+			exitCode = 1
+		}
+	}
 
+	if len(*planFiles) > 0 {
+		if err := cmd.TopicPlan(client, *planFiles); err != nil {
+			log.Printf("Ошибка построения плана изменений топиков: %v", err)
+			exitCode = 1
 		}
 	}
 
-	// if *whoTopicPart != "" {
-	// 	data, replicaBrokerId, err := cmd.WhoTopicPart(client, *whoTopicPart)
-	// 	if err != nil {
-	// 		log.Printf("Ошибка вывода списка партиций топиков: %v", err)
-	// 	} else {
-	// 		for topic, brokers := range data {
-	// 			log.Printf("Топик: %s, Брокеры: %v", topic, brokers)
-	// 		}
-	// 		for topic, brokers := range replicaBrokerId {
-	// 			log.Printf("Топик: %s, Брокеры: %v", topic, brokers)
-	// 		}
-	// 	}
-	// }
+	if len(*applyFiles) > 0 {
+		if err := cmd.TopicApply(client, *applyFiles); err != nil {
+			log.Printf("============================================================================")
+			log.Printf("❌ Задача по применению изменений топиков, не выполнена!")
+			log.Printf("Ошибка: %v", err)
+			log.Printf("============================================================================")
+			exitCode = 1
+		} else {
+			log.Printf("============================================================================")
+			log.Printf("✅ Задача по применению изменений топиков, успешно выполнена!")
+			log.Printf("============================================================================")
+		}
+	}
+
+	if *groupListFlag {
+		if err := cmd.GroupList(client, *groupPattern, *topicPattern); err != nil {
+			log.Printf("Ошибка получения списка групп потребителей: %v", err)
+			exitCode = 1
+		}
+	}
+
+	if *groupDescribeFlag {
+		if err := cmd.GroupDescribe(client, *groupPattern, *topicPattern); err != nil {
+			log.Printf("Ошибка получения описания групп потребителей: %v", err)
+			exitCode = 1
+		}
+	}
+
+	if *resetOffsetsMode != "" {
+		if err := cmd.GroupResetOffsets(client, *groupPattern, *topicPattern, *resetOffsetsMode, *resetValue, *executeFlag); err != nil {
+			log.Printf("============================================================================")
+			log.Printf("❌ Задача по сбросу смещений группы потребителей, не выполнена!")
+			log.Printf("Ошибка: %v", err)
+			log.Printf("============================================================================")
+			exitCode = 1
+		} else if *executeFlag {
+			log.Printf("============================================================================")
+			log.Printf("✅ Задача по сбросу смещений группы потребителей, успешно выполнена!")
+			log.Printf("============================================================================")
+		}
+	}
+
+	if *groupDeleteOffsetsFlag {
+		if err := cmd.GroupDeleteOffsets(client, *groupPattern, *topicPattern); err != nil {
+			log.Printf("Ошибка при удалении смещений группы потребителей: %v", err)
+			exitCode = 1
+		}
+	}
+
+	if *groupDeleteFlag {
+		if err := cmd.GroupDelete(client, *groupPattern); err != nil {
+			log.Printf("Ошибка при удалении групп потребителей: %v", err)
+			exitCode = 1
+		} else {
+			log.Printf("============================================================================")
+			log.Printf("✅ Задача по удалению групп потребителей, успешно выполнена!")
+			log.Printf("============================================================================")
+		}
+	}
+
+	if *consumeTopic != "" {
+		if err := cmd.Consume(client, *consumeTopic, *partitionFlag, *offsetFlag, *fromTimeFlag, *limitFlag, *followFlag, *outputFlag); err != nil {
+			log.Printf("Ошибка чтения сообщений топика: %v", err)
+			exitCode = 1
+		}
+	}
+
+	if *produceTopic != "" {
+		if err := cmd.Produce(client, *produceTopic, *produceFile, *inputFlag, *keySeparatorFlag, *partitionFlag, *compressionFlag, *acksFlag, *idempotentFlag); err != nil {
+			log.Printf("Ошибка записи сообщений в топик: %v", err)
+			exitCode = 1
+		}
+	}
+
+	// Инструмент Kafka может изменять кластер, поэтому выполняется только после проверки ID кластера
+	if *kafkaTool != "" {
+		if err := cmd.KafkaTool(*kafkaTool, pflag.Args()); err != nil {
+			log.Printf("Ошибка выполнения инструмента Kafka: %v", err)
+			exitCode = 1
+		}
+	}
+
+	if *changeReplicationFactorFile != "" {
+		if err := cmd.TopicChangeReplicationFactor(client, *changeReplicationFactorFile); err != nil {
+			log.Printf("============================================================================")
+			log.Printf("❌ Задача по изменению фактора репликации, не выполнена!")
+			log.Printf("Ошибка: %v", err)
+			log.Printf("============================================================================")
+			exitCode = 1
+		} else {
+			log.Printf("============================================================================")
+			log.Printf("✅ Задача по изменению фактора репликации, успешно запущена! Проверка: -v, откат: -r")
+			log.Printf("============================================================================")
+		}
+	}
 
 	// Обработка сигналов для корректного закрытия
 	c := make(chan os.Signal, 1)
@@ -259,6 +497,14 @@ func main() {
 		}
 		os.Exit(0)
 	}()
+
+	// Общий клиент команд закрывается один раз после выполнения всех команд
+	if err := client.Close(); err != nil {
+		log.Printf("Error closing client: %v", err)
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
 
 var (
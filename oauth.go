@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Токен обновляется заранее, за это время до истечения срока действия
+const oauthTokenRefreshMargin = 30 * time.Second
+
+// Провайдер токенов OAUTHBEARER по схеме client credentials.
+// Токен кэшируется и запрашивается заново перед истечением срока действия.
+// TokenEndpoint и HTTPClient задаются явно, поэтому провайдер можно проверить на локальном HTTP сервере
+type oauthTokenProvider struct {
+	TokenEndpoint string
+	ClientID      string
+	ClientSecret  string
+	Scopes        []string
+	Extensions    map[string]string
+	HTTPClient    *http.Client
+
+	// Текущее время, подменяется для проверки обновления токена
+	Now func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// Ответ token endpoint (RFC 6749, раздел 5.1)
+type oauthTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *oauthTokenProvider) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+// Token реализует sarama.AccessTokenProvider
+func (p *oauthTokenProvider) Token() (*sarama.AccessToken, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token == "" || !p.now().Before(p.expiresAt.Add(-oauthTokenRefreshMargin)) {
+		if err := p.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	return &sarama.AccessToken{Token: p.token, Extensions: p.Extensions}, nil
+}
+
+// Запрашиваем новый токен у token endpoint
+func (p *oauthTokenProvider) refresh() error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	request, err := http.NewRequest(http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("ошибка формирования запроса токена: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	issuedAt := p.now()
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса токена у %s: %v", p.TokenEndpoint, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("ошибка чтения ответа %s: %v", p.TokenEndpoint, err)
+	}
+	var tokenResponse oauthTokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil && response.StatusCode == http.StatusOK {
+		return fmt.Errorf("некорректный ответ %s: %v", p.TokenEndpoint, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		if tokenResponse.Error != "" {
+			return fmt.Errorf("token endpoint вернул %s: %s", response.Status, strings.TrimSpace(tokenResponse.Error+" "+tokenResponse.ErrorDescription))
+		}
+		return fmt.Errorf("token endpoint вернул %s", response.Status)
+	}
+	if tokenResponse.AccessToken == "" {
+		return fmt.Errorf("token endpoint не вернул access_token")
+	}
+
+	p.token = tokenResponse.AccessToken
+	if tokenResponse.ExpiresIn > 0 {
+		p.expiresAt = issuedAt.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	} else {
+		// Срок действия не указан, токен запрашивается при каждом подключении
+		p.expiresAt = issuedAt
+	}
+	return nil
+}
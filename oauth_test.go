@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Локальный token endpoint: проверяет запрос client credentials и выдает токены token-1, token-2, ...
+func newTestTokenServer(t *testing.T, handler func(w http.ResponseWriter, issued int64)) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("метод %s, ожидался POST", r.Method)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ошибка разбора формы: %v", err)
+		}
+		if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+			t.Errorf("grant_type %q, ожидался client_credentials", grantType)
+		}
+		if scope := r.PostForm.Get("scope"); scope != "kafka profile" {
+			t.Errorf("scope %q, ожидался %q", scope, "kafka profile")
+		}
+		if user, password, ok := r.BasicAuth(); !ok || user != "kafkamap" || password != "s3cret" {
+			t.Errorf("basic auth %q/%q, ожидался kafkamap/s3cret", user, password)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, requests.Add(1))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newTestTokenProvider(server *httptest.Server, now *time.Time) *oauthTokenProvider {
+	return &oauthTokenProvider{
+		TokenEndpoint: server.URL,
+		ClientID:      "kafkamap",
+		ClientSecret:  "s3cret",
+		Scopes:        []string{"kafka", "profile"},
+		Extensions:    map[string]string{"logicalCluster": "lkc-1"},
+		HTTPClient:    server.Client(),
+		Now:           func() time.Time { return *now },
+	}
+}
+
+func TestOAuthTokenProviderCachingAndRefresh(t *testing.T) {
+	server, requests := newTestTokenServer(t, func(w http.ResponseWriter, issued int64) {
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":300}`, issued)
+	})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	provider := newTestTokenProvider(server, &now)
+
+	tests := []struct {
+		name         string
+		elapsed      time.Duration
+		wantToken    string
+		wantRequests int64
+	}{
+		{"первый запрос", 0, "token-1", 1},
+		{"токен из кэша", time.Minute, "token-1", 1},
+		{"до запаса обновления", 300*time.Second - oauthTokenRefreshMargin - time.Second, "token-1", 1},
+		{"в пределах запаса обновления", 300*time.Second - oauthTokenRefreshMargin, "token-2", 2},
+		{"новый токен из кэша", 300*time.Second - oauthTokenRefreshMargin + time.Minute, "token-2", 2},
+		{"после истечения нового токена", 2 * 300 * time.Second, "token-3", 3},
+	}
+	start := now
+	for _, tt := range tests {
+		now = start.Add(tt.elapsed)
+		token, err := provider.Token()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if token.Token != tt.wantToken {
+			t.Errorf("%s: токен %q, ожидался %q", tt.name, token.Token, tt.wantToken)
+		}
+		if token.Extensions["logicalCluster"] != "lkc-1" {
+			t.Errorf("%s: расширения %v", tt.name, token.Extensions)
+		}
+		if got := requests.Load(); got != tt.wantRequests {
+			t.Errorf("%s: запросов к token endpoint %d, ожидалось %d", tt.name, got, tt.wantRequests)
+		}
+	}
+}
+
+func TestOAuthTokenProviderWithoutExpiry(t *testing.T) {
+	server, requests := newTestTokenServer(t, func(w http.ResponseWriter, issued int64) {
+		fmt.Fprintf(w, `{"access_token":"token-%d"}`, issued)
+	})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	provider := newTestTokenProvider(server, &now)
+
+	for i := 1; i <= 2; i++ {
+		token, err := provider.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("token-%d", i); token.Token != want {
+			t.Errorf("токен %q, ожидался %q", token.Token, want)
+		}
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("запросов к token endpoint %d, ожидалось 2", got)
+	}
+}
+
+func TestOAuthTokenProviderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"ошибка OAuth", http.StatusUnauthorized, `{"error":"invalid_client","error_description":"bad secret"}`, "401 Unauthorized: invalid_client bad secret"},
+		{"ошибка OAuth без описания", http.StatusBadRequest, `{"error":"invalid_scope"}`, "400 Bad Request: invalid_scope"},
+		{"ответ не JSON", http.StatusBadGateway, `<html>bad gateway</html>`, "token endpoint вернул 502 Bad Gateway"},
+		{"нет access_token", http.StatusOK, `{"token_type":"Bearer","expires_in":300}`, "не вернул access_token"},
+		{"некорректный JSON", http.StatusOK, `{"access_token":`, "некорректный ответ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestTokenServer(t, func(w http.ResponseWriter, issued int64) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			provider := newTestTokenProvider(server, &now)
+
+			token, err := provider.Token()
+			if err == nil {
+				t.Fatalf("ожидалась ошибка, получен токен %q", token.Token)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ошибка %q не содержит %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// После ошибки token endpoint токен запрашивается заново при следующем подключении
+func TestOAuthTokenProviderRetryAfterError(t *testing.T) {
+	server, requests := newTestTokenServer(t, func(w http.ResponseWriter, issued int64) {
+		if issued == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":300}`, issued)
+	})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	provider := newTestTokenProvider(server, &now)
+
+	if _, err := provider.Token(); err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	token, err := provider.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Token != "token-2" || requests.Load() != 2 {
+		t.Errorf("токен %q после %d запросов, ожидался token-2 после 2", token.Token, requests.Load())
+	}
+}